@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/pipeline"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/scraper"
+	"gopkg.in/yaml.v3"
+)
+
+// newFlagSet creates a flag set for a subcommand that reports errors instead of exiting.
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\nFlags:\n", os.Args[0], usageLine)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a subcommand, turning parse failures into usage errors.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil {
+		if fs.NArg() > 0 {
+			return usageError{msg: "unexpected arguments: " + strings.Join(fs.Args(), " ")}
+		}
+		return nil
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return usageError{msg: err.Error()}
+}
+
+// parseConfigFlags parses the flags of a subcommand that reads a pipeline config. bind
+// registers the subcommand's flags, using the current config values as defaults. The
+// flags are parsed once to find -config, and again on top of the loaded config so that
+// flags given on the command line override the file.
+func parseConfigFlags(name, usageLine string, args []string, bind func(fs *flag.FlagSet, cfg *pipeline.Config)) (*pipeline.Config, error) {
+	cfg := pipeline.DefaultConfig()
+	fs := newFlagSet(name, usageLine)
+	configPath := fs.String("config", "", "pipeline config file (YAML or JSON) to read settings from")
+	bind(fs, cfg)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	if *configPath == "" {
+		return cfg, nil
+	}
+
+	cfg, err := pipeline.LoadConfig(*configPath)
+	if err != nil {
+		return nil, err
+	}
+	fs = newFlagSet(name, usageLine)
+	fs.String("config", "", "")
+	bind(fs, cfg)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// requireFlag returns a usage error if a required string flag was left empty.
+func requireFlag(name, value string) error {
+	if value == "" {
+		return usageError{msg: "missing required flag -" + name}
+	}
+	return nil
+}
+
+func runPipeline(ctx context.Context, args []string) error {
+	cfg, err := parseConfigFlags("run", "run -config <file>", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {})
+	if err != nil {
+		return err
+	}
+	return pipeline.Run(ctx, cfg)
+}
+
+func runScrape(ctx context.Context, args []string) error {
+	var startLink, sourceType string
+	var onlyLinks bool
+	var changedSince time.Time
+	var include, exclude []string
+	cfg, err := parseConfigFlags("scrape", "scrape -start <url> -source <name> [flags]", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+		fs.StringVar(&startLink, "start", "", "index page to start crawling from, replaces the configured sources; the sitemap source starts from the robots.txt, root or a sitemap of a site")
+		fs.StringVar(&sourceType, "source", "", "source of the start link, one of "+sourceNames()+", chosen by host if empty")
+		fs.BoolVar(&onlyLinks, "only-links", false, "only collect recipe links, do not scrape the recipes")
+		fs.Var(dateFlag{&changedSince}, "since", "only crawl recipes whose sitemap entry changed on or after this date, e.g. 2023-06-01")
+		fs.Var(newStringsFlag(&include), "include", "regular expression of the sitemap links to crawl as recipes, may be repeated")
+		fs.Var(newStringsFlag(&exclude), "exclude", "regular expression of the sitemap links to leave out, may be repeated")
+		bindScrapeFlags(fs, cfg)
+	})
+	if err != nil {
+		return err
+	}
+
+	if startLink != "" {
+		cfg.Scrape.Sources = []pipeline.SourceConfig{{
+			Type:         sourceType,
+			StartLinks:   []string{startLink},
+			OnlyLinks:    onlyLinks,
+			ChangedSince: changedSince,
+			Include:      include,
+			Exclude:      exclude,
+		}}
+	}
+	if len(cfg.Scrape.Sources) == 0 {
+		return usageError{msg: "missing required flag -start or configured sources"}
+	}
+	return pipeline.RunScrape(ctx, cfg)
+}
+
+func runScrapeLinks(ctx context.Context, args []string) error {
+	var linksPath, sourceType string
+	cfg, err := parseConfigFlags("scrape-links", "scrape-links -links <file> -source <name> [flags]", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+		fs.StringVar(&linksPath, "links", "links.tmp", "file with one recipe link per line")
+		fs.StringVar(&sourceType, "source", "", "source of links whose host matches no source, one of "+sourceNames()+"; by default every link is scraped by the source of its host")
+		bindScrapeFlags(fs, cfg)
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.Scrape.Sources = []pipeline.SourceConfig{{
+		Type:      sourceType,
+		LinksFile: linksPath,
+	}}
+	return pipeline.RunScrape(ctx, cfg)
+}
+
+// sourceNames lists the built-in sources for flag usage messages.
+func sourceNames() string {
+	return strings.Join(scraper.DefaultSources.Names(), ", ")
+}
+
+// bindScrapeFlags registers the flags shared by the scraping subcommands.
+func bindScrapeFlags(fs *flag.FlagSet, cfg *pipeline.Config) {
+	fs.StringVar(&cfg.Scrape.OutputPath, "out", cfg.Scrape.OutputPath, "file to write scraped recipes to")
+	fs.Var(newStringsFlag(&cfg.Scrape.Sites), "site", "YAML site definition file or directory to load sources from, may be repeated; replaces the configured sites")
+	fs.StringVar(&cfg.Scrape.LinksPath, "links-out", cfg.Scrape.LinksPath, "file to write crawled recipe links to")
+	fs.StringVar(&cfg.Scrape.StateDir, "state-dir", cfg.Scrape.StateDir, "directory of the crawl state used to resume interrupted crawls")
+	fs.BoolVar(&cfg.Scrape.Fresh, "fresh", cfg.Scrape.Fresh, "discard the crawl state and start over")
+	fs.IntVar(&cfg.Scrape.MaxAttempts, "max-attempts", cfg.Scrape.MaxAttempts, "number of times a failing link is tried across runs")
+	fs.StringVar(&cfg.Scrape.SkippedLinksPath, "skipped-out", cfg.Scrape.SkippedLinksPath, "file to record links skipped because of robots.txt")
+	fs.Var(listFlag{&cfg.Scrape.RequiredFields}, "require", "comma separated fields a recipe is not kept without, of "+strings.Join(parser.Fields, ", "))
+	fs.StringVar(&cfg.Scrape.DiagnosticsPath, "diagnostics-out", cfg.Scrape.DiagnosticsPath, "file to record the fields each recipe is missing, empty to disable")
+	fs.IntVar(&cfg.Scrape.DriftWindow, "drift-window", cfg.Scrape.DriftWindow, "number of recent recipes of a source a field's success rate is taken over, 0 to disable drift detection")
+	fs.Float64Var(&cfg.Scrape.DriftThreshold, "drift-threshold", cfg.Scrape.DriftThreshold, "share of the drift window a field must be found in")
+	fs.StringVar(&cfg.Scrape.DriftAction, "drift-action", cfg.Scrape.DriftAction, "what to do when a field drifts below the threshold, abort or alert")
+	fs.StringVar(&cfg.Scrape.DriftSampleDir, "drift-dir", cfg.Scrape.DriftSampleDir, "directory to save the HTML of pages that missed a drifted field")
+	fs.StringVar(&cfg.Scrape.UserAgent, "user-agent", cfg.Scrape.UserAgent, "user agent sent with requests and matched against robots.txt")
+	fs.DurationVar(&cfg.Scrape.RequestTimeout, "timeout", cfg.Scrape.RequestTimeout, "maximum duration of a single request, 0 for no limit")
+	fs.StringVar(&cfg.Scrape.ProxyURL, "proxy", cfg.Scrape.ProxyURL, "proxy URL to send requests through")
+	fs.BoolVar(&cfg.Scrape.CookieJar, "cookies", cfg.Scrape.CookieJar, "keep cookies set by sites across requests")
+	fs.Var(headerFlag{&cfg.Scrape.Headers}, "header", "extra request header as 'Name: value', may be repeated")
+	fs.IntVar(&cfg.Scrape.MaxRetries, "max-retries", cfg.Scrape.MaxRetries, "number of immediate retries of a transient fetch failure")
+	fs.IntVar(&cfg.Scrape.Concurrency, "concurrency", cfg.Scrape.Concurrency, "maximum number of requests in flight across all hosts")
+	fs.Float64Var(&cfg.Scrape.RequestsPerSecond, "rps", cfg.Scrape.RequestsPerSecond, "requests per second allowed to each host")
+	fs.IntVar(&cfg.Scrape.Burst, "burst", cfg.Scrape.Burst, "number of requests a host may receive in a burst")
+	fs.DurationVar(&cfg.Scrape.Jitter, "jitter", cfg.Scrape.Jitter, "maximum random delay added before each request")
+}
+
+// stringsFlag is a repeatable flag whose values replace a list: the first value given
+// replaces the list, e.g. the one loaded from a config file, and the others are appended.
+type stringsFlag struct {
+	values *[]string
+	set    *bool
+}
+
+// newStringsFlag creates a stringsFlag of values. The flags of a subcommand are bound
+// anew for every parse, so values given on the command line are only added once.
+func newStringsFlag(values *[]string) stringsFlag {
+	return stringsFlag{values: values, set: new(bool)}
+}
+
+func (f stringsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ", ")
+}
+
+func (f stringsFlag) Set(value string) error {
+	if !*f.set {
+		*f.set = true
+		*f.values = nil
+	}
+	*f.values = append(*f.values, value)
+	return nil
+}
+
+// listFlag is a flag whose comma separated values replace a list.
+type listFlag struct {
+	values *[]string
+}
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	values := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	*f.values = values
+	return nil
+}
+
+// dateFlag is a flag of a date like 2023-06-01, or a time like 2023-06-01T12:00:00Z.
+type dateFlag struct {
+	value *time.Time
+}
+
+func (f dateFlag) String() string {
+	if f.value == nil || f.value.IsZero() {
+		return ""
+	}
+	return f.value.Format(time.RFC3339)
+}
+
+func (f dateFlag) Set(value string) error {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			*f.value = t
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a date like 2023-06-01", value)
+}
+
+// headerFlag is a repeatable flag that adds "Name: value" request headers to a map.
+type headerFlag struct {
+	headers *map[string]string
+}
+
+func (f headerFlag) String() string {
+	if f.headers == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f.headers))
+	for key, value := range *f.headers {
+		pairs = append(pairs, key+": "+value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (f headerFlag) Set(value string) error {
+	key, val, found := strings.Cut(value, ":")
+	if !found || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("header %q is not of the form 'Name: value'", value)
+	}
+	if *f.headers == nil {
+		*f.headers = make(map[string]string)
+	}
+	(*f.headers)[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	return nil
+}
+
+func runClean(ctx context.Context, args []string) error {
+	cfg, err := parseConfigFlags("clean", "clean -in <file> -out <file> [flags]", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+		fs.StringVar(&cfg.Clean.InputPath, "in", cfg.Clean.InputPath, "scraped recipe file to clean (required)")
+		fs.StringVar(&cfg.Clean.OutputPath, "out", cfg.Clean.OutputPath, "file to write the cleaned recipes to")
+		fs.BoolVar(&cfg.Clean.RemoveBlankImages, "remove-blank-images", cfg.Clean.RemoveBlankImages, "remove recipes without an image")
+		fs.BoolVar(&cfg.Clean.RemoveBlankDescriptions, "remove-blank-descriptions", cfg.Clean.RemoveBlankDescriptions, "remove recipes without a description")
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireFlag("in", cfg.Clean.InputPath); err != nil {
+		return err
+	}
+	return pipeline.RunClean(cfg)
+}
+
+func runProcessIngredients(ctx context.Context, args []string) error {
+	cfg, err := parseConfigFlags("process-ingredients", "process-ingredients -in <file> [flags]", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+		fs.StringVar(&cfg.Ingredients.InputPath, "in", cfg.Ingredients.InputPath, "cleaned raw recipe file to process (required)")
+		fs.StringVar(&cfg.Ingredients.ExcludePath, "exclude", cfg.Ingredients.ExcludePath, "raw recipe file whose source URLs are skipped, e.g. already processed recipes")
+		bindProcessFlags(fs, &cfg.Ingredients)
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireFlag("in", cfg.Ingredients.InputPath); err != nil {
+		return err
+	}
+	if cfg.Ingredients.Workers < 1 {
+		return usageError{msg: "-workers must be at least 1"}
+	}
+
+	_, err = pipeline.RunProcessIngredients(ctx, cfg)
+	return err
+}
+
+func runProcessAttributes(ctx context.Context, args []string) error {
+	cfg, err := parseConfigFlags("process-attributes", "process-attributes -in <file> [flags]", args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+		fs.StringVar(&cfg.Attributes.InputPath, "in", cfg.Attributes.InputPath, "processed recipe file to tag (required)")
+		bindProcessFlags(fs, &cfg.Attributes)
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireFlag("in", cfg.Attributes.InputPath); err != nil {
+		return err
+	}
+	if cfg.Attributes.Workers < 1 {
+		return usageError{msg: "-workers must be at least 1"}
+	}
+
+	_, err = pipeline.RunProcessAttributes(ctx, cfg)
+	return err
+}
+
+// bindProcessFlags registers the flags shared by the processing subcommands.
+func bindProcessFlags(fs *flag.FlagSet, stage *pipeline.ProcessConfig) {
+	fs.StringVar(&stage.OutputPath, "out", stage.OutputPath, "file to write processed recipes to, defaults to a timestamped file in recipes/")
+	fs.IntVar(&stage.Workers, "workers", stage.Workers, "number of concurrent workers")
+	fs.IntVar(&stage.SampleSize, "n", stage.SampleSize, "number of randomly sampled recipes to process, 0 processes all")
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("export", "export -in <file> -out <file> [flags]")
+	inPath := fs.String("in", "", "processed recipe file to export (required)")
+	outPath := fs.String("out", "", "file to write the exported recipes to (required)")
+	format := fs.String("format", "json", "output format, json or yaml")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag("in", *inPath); err != nil {
+		return err
+	}
+	if err := requireFlag("out", *outPath); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "yaml" {
+		return usageError{msg: fmt.Sprintf("unknown format %q", *format)}
+	}
+
+	recipes := make([]*recipe.Recipe, 0)
+	if err := pipeline.ReadYAML(*inPath, &recipes); err != nil {
+		return err
+	}
+
+	outFile, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	if *format == "yaml" {
+		return yaml.NewEncoder(outFile).Encode(recipes)
+	}
+	enc := json.NewEncoder(outFile)
+	enc.SetIndent("", "  ")
+	return enc.Encode(recipes)
+}
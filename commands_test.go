@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/pipeline"
+)
+
+func TestParseConfigFlags(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "pipeline.yaml")
+	config := "scrape:\n  output_path: from-config.yaml\n  sites:\n    - config-sites\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantOutput  string
+		wantSites   []string
+		wantInclude []string
+	}{
+		{"defaults", nil, pipeline.DefaultConfig().Scrape.OutputPath, nil, nil},
+		{"config", []string{"-config", configPath}, "from-config.yaml", []string{"config-sites"}, nil},
+		{
+			"flags override config",
+			[]string{"-config", configPath, "-out", "from-flag.yaml", "-site", "a", "-site", "b"},
+			"from-flag.yaml", []string{"a", "b"}, nil,
+		},
+		{
+			"repeated flags once",
+			[]string{"-include", "/recipes/", "-include", "/recipe/", "-config", configPath},
+			"from-config.yaml", []string{"config-sites"}, []string{"/recipes/", "/recipe/"},
+		},
+	}
+	for _, test := range tests {
+		var include []string
+		cfg, err := parseConfigFlags("scrape", "scrape [flags]", test.args, func(fs *flag.FlagSet, cfg *pipeline.Config) {
+			fs.Var(newStringsFlag(&include), "include", "")
+			bindScrapeFlags(fs, cfg)
+		})
+		if err != nil {
+			t.Errorf("%s: parseConfigFlags() = %v", test.name, err)
+			continue
+		}
+		if cfg.Scrape.OutputPath != test.wantOutput {
+			t.Errorf("%s: output path = %q, want %q", test.name, cfg.Scrape.OutputPath, test.wantOutput)
+		}
+		if len(cfg.Scrape.Sites) > 0 || len(test.wantSites) > 0 {
+			if !reflect.DeepEqual(cfg.Scrape.Sites, test.wantSites) {
+				t.Errorf("%s: sites = %q, want %q", test.name, cfg.Scrape.Sites, test.wantSites)
+			}
+		}
+		if !reflect.DeepEqual(include, test.wantInclude) {
+			t.Errorf("%s: include = %q, want %q", test.name, include, test.wantInclude)
+		}
+	}
+}
@@ -1,178 +1,99 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-
-	"github.com/CS446-S23-Group35/RecipeScraper/pkg/processor"
-	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
-	"gopkg.in/yaml.v3"
+	"os/signal"
+	"syscall"
 )
 
-func main() {
-	//////////////////////
-	// Scraping
-	//////////////////////
-
-	// cfg := scraper.Config{
-	// 	StartLink:  "https://www.foodnetwork.com/recipes/recipes-a-z/l/p/12",
-	// 	SourceType: "foodnetwork",
-	// 	OutputPath: "recipes.yaml",
-	// 	OnlyLinks:  true,
-	// }
-
-	// scraper := scraper.NewScraper(cfg)
-	// err := scraper.Scrape(context.Background())
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	// err := scraper.ScrapeFromLinksFile(context.Background(), "links.tmp")
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	//////////////////////
-	// Cleaning
-	//////////////////////
-
-	// inFile, err := os.Open("recipes/recipesNoImages.yaml")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// defer inFile.Close()
-
-	// outFile, err := os.Create("recipes/recipes.yaml")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// defer outFile.Close()
-
-	// c := cleaner.NewFileCleaner(inFile, outFile)
-	// if err := c.Clean(); err != nil {
-	// 	fmt.Println("error deduping: " + err.Error())
-	// }
-
-	//////////////////////
-	// Prompting
-	//////////////////////
-
-	// 	openai := prompter.NewOpenAIPrompter()
-	// 	ings := `1/2 cup brandy
-	// 6 nectarines, skin left on, halved, pit removed
-	// 1 tablespoon sugar, to flambe
-	// 1/2 cup milk
-	// 1/2 cup heavy cream
-	// 1 vanilla pod or 3 teaspoons vanilla extract or 1 tbps pure vanilla extract
-	// 1 tablespoon lemon liqueur or 3 teaspoons lemon extract (recommended: Limoncello)
-	// 1/4 cup sugar, for lemon cream
-	// 4 egg yolks
-	// 1 tablespoon finely grated lemon zest
-	// 1 pint blackberries`
-	// ings := `- Cooking spray
-	// - 2 graham crackers
-	// - 1 tablespoon unsalted butter, melted
-	// - 1 teaspoon sugar
-	// - 3 ounces cream cheese, softened
-	// - 3 tablespoons sour cream
-	// - 3 tablespoons sugar
-	// - 1 large egg
-	// - 2 teaspoons all-purpose flour
-	// - 1/2 teaspoon lemon zest
-	// - 1/2 teaspoon pure vanilla extract
-	// - Pinch of salt
-	// - 2 large strawberries, finely chopped
-	// - 1 teaspoon strawberry jam`
-
-	// req := prompter.NewParseIngredientsRequest(ings)
-
-	// resp, err := openai.MakeRequest(req)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println(resp.Choices[0].Text)
-
-	////////////////////////////////////////
-	// Processing - First step, ingredients
-	////////////////////////////////////////
-
-	// file, err := os.Open("recipes/recipes.yaml")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// defer file.Close()
-
-	// recipes := make([]*recipe.RawRecipe, 0)
-	// err = yaml.NewDecoder(file).Decode(&recipes)
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	// otherFile, err := os.Open("recipes/referenceRecipes.yaml")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// defer otherFile.Close()
-	// filterBase := make([]*recipe.RawRecipe, 0)
-	// err = yaml.NewDecoder(otherFile).Decode(&filterBase)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// urlSet := make(map[string]bool)
-	// for _, r := range filterBase {
-	// 	urlSet[r.Metadata.SourceURL] = true
-	// }
-
-	// filtered := make([]*recipe.RawRecipe, 0, len(recipes))
-	// for _, r := range recipes {
-	// 	if !urlSet[r.Metadata.SourceURL] {
-	// 		filtered = append(filtered, r)
-	// 	}
-	// }
+// Exit codes returned by the command line interface.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
 
-	// processor := processor.NewRecipeProcessor()
-	// defer processor.Close()
+// command is a single subcommand of the CLI. run receives the arguments
+// following the subcommand name, and a context that is cancelled on Ctrl-C.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
 
-	// err = processor.ProcessRawRecipes(filtered, len(filtered), 3)
-	// if err != nil {
-	// 	panic(err)
-	// }
+var commands = []command{
+	{"run", "run every stage of a pipeline config file", runPipeline},
+	{"scrape", "crawl index pages from a start link and scrape the recipes found", runScrape},
+	{"scrape-links", "scrape recipes from a file of recipe links", runScrapeLinks},
+	{"clean", "remove blank and duplicate recipes from a scraped recipe file", runClean},
+	{"process-ingredients", "parse the ingredients of raw recipes with OpenAI", runProcessIngredients},
+	{"process-attributes", "tag processed recipes with dietary attributes with OpenAI", runProcessAttributes},
+	{"export", "export a recipe file as JSON or YAML", runExport},
+}
 
-	////////////////////////////////////////
-	// Processing - Second step, attributes
-	////////////////////////////////////////
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	file, err := os.Open("recipes/Backup.yaml")
-	if err != nil {
-		panic(err)
+func run(args []string) int {
+	if len(args) < 1 {
+		usage()
+		return exitUsage
 	}
-	defer file.Close()
-	filterBase := make([]*recipe.Recipe, 0)
-	err = yaml.NewDecoder(file).Decode(&filterBase)
-	if err != nil {
-		panic(err)
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" || name == "-help" {
+		usage()
+		return exitOK
 	}
 
-	groupedByUrl := make(map[string][]*recipe.Recipe)
-	for _, r := range filterBase {
-		groupedByUrl[r.Metadata.SourceURL] = append(groupedByUrl[r.Metadata.SourceURL], r)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := cmd.run(ctx, args[1:])
+		stop()
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, context.Canceled):
+			fmt.Fprintf(os.Stderr, "%s: interrupted\n", cmd.name)
+			return exitError
+		case errors.As(err, &usageError{}):
+			fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.name, err.Error())
+			return exitUsage
+		default:
+			fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.name, err.Error())
+			return exitError
+		}
 	}
 
-	processor := processor.NewRecipeProcessor()
-	defer processor.Close()
-	err = processor.ProcessRecipeAttributes(groupedByUrl, len(groupedByUrl), 2)
-	if err != nil {
-		panic(err)
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	return exitUsage
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.summary)
 	}
-	// for _, v := range groupedByUrl {
-	// 	res, err := processor.ProcessAttributes(v)
-	// 	if err != nil {
-	// 		fmt.Println(err)
-	// 	} else {
-	// 		for _, r := range res {
-	// 			fmt.Println(r.Name)
-	// 			fmt.Println(r.Metadata.Dietary)
-	// 		}
-	// 	}
-	// }
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> --help' for the flags of a command.\n", os.Args[0])
+}
+
+// usageError is returned by a command when it was invoked with bad flags or arguments.
+type usageError struct {
+	msg string
+}
 
+func (e usageError) Error() string {
+	return e.msg
 }
@@ -11,19 +11,41 @@ type Cleaner interface {
 	Clean() error
 }
 
+// Config configures the files and filters of a cleaning run.
+type Config struct {
+	InputPath  string `yaml:"input_path"`
+	OutputPath string `yaml:"output_path"`
+
+	RemoveBlankImages       bool `yaml:"remove_blank_images"`
+	RemoveBlankDescriptions bool `yaml:"remove_blank_descriptions"`
+}
+
+// DefaultConfig returns the cleaner configuration used when none is given.
+func DefaultConfig() Config {
+	return Config{
+		RemoveBlankImages:       true,
+		RemoveBlankDescriptions: true,
+	}
+}
+
 type FileCleaner struct {
 	inFile            *os.File
 	outFile           *os.File
 	BlankImageRemover *BlankRemover
 	DedupSorter       *DedupSorter
+
+	RemoveBlankImages       bool
+	RemoveBlankDescriptions bool
 }
 
 func NewFileCleaner(inFile, outFile *os.File) *FileCleaner {
 	return &FileCleaner{
-		inFile:            inFile,
-		outFile:           outFile,
-		BlankImageRemover: NewBlankRemover(),
-		DedupSorter:       NewDedupSorter(),
+		inFile:                  inFile,
+		outFile:                 outFile,
+		BlankImageRemover:       NewBlankRemover(),
+		DedupSorter:             NewDedupSorter(),
+		RemoveBlankImages:       true,
+		RemoveBlankDescriptions: true,
 	}
 }
 
@@ -37,8 +59,12 @@ func (fc *FileCleaner) Clean() error {
 
 	println("recipesRaw len: ", len(recipesRaw))
 
-	recipesRaw = fc.BlankImageRemover.RemoveBlankImages(recipesRaw)
-	recipesRaw = fc.BlankImageRemover.RemoveBlankDescription(recipesRaw)
+	if fc.RemoveBlankImages {
+		recipesRaw = fc.BlankImageRemover.RemoveBlankImages(recipesRaw)
+	}
+	if fc.RemoveBlankDescriptions {
+		recipesRaw = fc.BlankImageRemover.RemoveBlankDescription(recipesRaw)
+	}
 	recipesRaw = fc.DedupSorter.DedupSort(recipesRaw)
 
 	println("Cleaned recipesRaw len: ", len(recipesRaw))
@@ -36,6 +36,26 @@ func (ds *DedupSorter) DedupSort(recipes []recipe.RawRecipe) []recipe.RawRecipe
 			r.IngredientDescriptions[i] = strings.Trim(nonUnicodeStep, " \n\t")
 		}
 
+		for i, item := range r.Equipment {
+			r.Equipment[i] = strings.Trim(ds.nonUnicodeReplace.Replace(item), " \n\t")
+		}
+
+		// Groups and sections hold the same texts, which are cleaned the same way.
+		for _, group := range r.IngredientGroups {
+			for i, ing := range group.Ingredients {
+				group.Ingredients[i] = strings.Trim(ds.nonUnicodeReplace.Replace(ing), " \n\t")
+			}
+		}
+		for _, section := range r.StepSections {
+			for i, step := range section.Steps {
+				section.Steps[i] = strings.Trim(ds.nonUnicodeReplace.Replace(step), " \n\t")
+			}
+		}
+
+		// Of several copies of a recipe, keep the most recently updated one.
+		if kept, ok := recipeMap[r.Metadata.SourceURL]; ok && r.Metadata.LastUpdated().Before(kept.Metadata.LastUpdated()) {
+			continue
+		}
 		recipeMap[r.Metadata.SourceURL] = r
 	}
 
@@ -44,6 +64,7 @@ func (ds *DedupSorter) DedupSort(recipes []recipe.RawRecipe) []recipe.RawRecipe
 		recipes[i] = r
 		i++
 	}
+	recipes = recipes[:i]
 
 	sort.Slice(recipes, func(i, j int) bool {
 		return recipes[i].Name < recipes[j].Name
@@ -0,0 +1,58 @@
+package cleaner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+)
+
+func TestDedupSortKeepsLastUpdated(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, time.June, d, 0, 0, 0, 0, time.UTC) }
+	copyOf := func(link, description string, published, updated time.Time) recipe.RawRecipe {
+		r := recipe.RawRecipe{Name: "Pie " + link, Description: description}
+		r.Metadata.SourceURL = link
+		r.Metadata.PublishedAt, r.Metadata.UpdatedAt = published, updated
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		recipes []recipe.RawRecipe
+		want    string
+	}{
+		{"updated later", []recipe.RawRecipe{copyOf("a", "new", day(1), day(5)), copyOf("a", "old", day(1), day(2))}, "new"},
+		{"updated later, scraped last", []recipe.RawRecipe{copyOf("a", "old", day(1), day(2)), copyOf("a", "new", day(1), day(5))}, "new"},
+		// A copy that was never updated is as new as its publish date.
+		{"published later", []recipe.RawRecipe{copyOf("a", "new", day(6), time.Time{}), copyOf("a", "old", day(1), day(5))}, "new"},
+		// Copies without dates keep the last one scraped.
+		{"no dates", []recipe.RawRecipe{copyOf("a", "old", time.Time{}, time.Time{}), copyOf("a", "new", time.Time{}, time.Time{})}, "new"},
+	}
+	for _, test := range tests {
+		got := NewDedupSorter().DedupSort(test.recipes)
+		if len(got) != 1 || got[0].Description != test.want {
+			t.Errorf("%s: DedupSort() = %+v, want only the %s copy", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDedupSortCleansAndSorts(t *testing.T) {
+	recipes := []recipe.RawRecipe{
+		{Name: " Waffles\n", Steps: []string{" Mix. "}},
+		{
+			Name:                   "Apple Pie",
+			IngredientDescriptions: []string{"1 apple "},
+			IngredientGroups:       []recipe.IngredientGroup{{Name: "Filling", Ingredients: []string{"1 apple "}}},
+		},
+	}
+	recipes[0].Metadata.SourceURL = "b"
+	recipes[1].Metadata.SourceURL = "a"
+
+	got := NewDedupSorter().DedupSort(recipes)
+	if len(got) != 2 || got[0].Name != "Apple Pie" || got[1].Name != "Waffles" {
+		t.Fatalf("DedupSort() = %+v, want Apple Pie and Waffles in order", got)
+	}
+	if got[1].Steps[0] != "Mix." || got[0].IngredientDescriptions[0] != "1 apple" || got[0].IngredientGroups[0].Ingredients[0] != "1 apple" {
+		t.Errorf("DedupSort() = %+v, want trimmed texts without non-breaking spaces", got)
+	}
+}
@@ -9,6 +9,8 @@ import (
 type LinkPage struct {
 	Links    []string
 	NextPage string
+	// IndexPages are further index pages to crawl, e.g. subcategories.
+	IndexPages []string
 }
 
 // LinkSource is an interface for parsing links from an HTML home page.
@@ -17,3 +19,10 @@ type LinkPage struct {
 type LinkSource interface {
 	GetLinks(*html.Node) (*LinkPage, error)
 }
+
+// PageLinkSource is a LinkSource of index pages that are not HTML, like XML sitemaps. The
+// scraper gives it the index pages as they were fetched instead of parsing them as HTML.
+type PageLinkSource interface {
+	LinkSource
+	GetPageLinks(page []byte) (*LinkPage, error)
+}
@@ -0,0 +1,89 @@
+package linksource
+
+import (
+	"fmt"
+	"net/url"
+
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// SelectorLinkSourceConfig defines how to find recipe links on the index pages of a site
+// with CSS selectors. It is read from the links section of a YAML site definition.
+type SelectorLinkSourceConfig struct {
+	LinkSelector     string `yaml:"link_selector"`
+	NextPageSelector string `yaml:"next_page_selector"`
+	// IndexPageSelector selects links to further index pages, e.g. categories.
+	IndexPageSelector string `yaml:"index_page_selector"`
+	// BaseURL resolves relative links, e.g. https://www.example.com/recipes/.
+	BaseURL string `yaml:"base_url"`
+}
+
+// SelectorLinkSource finds recipe links with the CSS selectors of a site definition.
+type SelectorLinkSource struct {
+	linkSelector      css.Selector
+	nextPageSelector  css.Selector
+	indexPageSelector css.Selector
+	baseURL           *url.URL
+}
+
+// NewSelectorLinkSource compiles the selectors of cfg into a SelectorLinkSource, or
+// returns an error if any is invalid.
+func NewSelectorLinkSource(cfg SelectorLinkSourceConfig) (*SelectorLinkSource, error) {
+	s := &SelectorLinkSource{}
+	var err error
+	if s.linkSelector, err = css.Compile(cfg.LinkSelector); err != nil {
+		return nil, fmt.Errorf("invalid link selector: %w", err)
+	}
+	if cfg.NextPageSelector != "" {
+		if s.nextPageSelector, err = css.Compile(cfg.NextPageSelector); err != nil {
+			return nil, fmt.Errorf("invalid next page selector: %w", err)
+		}
+	}
+	if cfg.IndexPageSelector != "" {
+		if s.indexPageSelector, err = css.Compile(cfg.IndexPageSelector); err != nil {
+			return nil, fmt.Errorf("invalid index page selector: %w", err)
+		}
+	}
+	if s.baseURL, err = url.Parse(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	return s, nil
+}
+
+func (s SelectorLinkSource) GetLinks(node *html.Node) (*LinkPage, error) {
+	links := s.hrefs(s.linkSelector, node)
+	if len(links) == 0 {
+		return nil, fmt.Errorf("did not find any recipe links")
+	}
+
+	nextPage := ""
+	if nextPages := s.hrefs(s.nextPageSelector, node); len(nextPages) > 0 {
+		nextPage = nextPages[0]
+	}
+
+	return &LinkPage{
+		Links:      links,
+		NextPage:   nextPage,
+		IndexPages: s.hrefs(s.indexPageSelector, node),
+	}, nil
+}
+
+// hrefs returns the resolved href of every element selected.
+func (s SelectorLinkSource) hrefs(selector css.Selector, node *html.Node) []string {
+	links := make([]string, 0)
+	if selector == nil {
+		return links
+	}
+	for _, linkNode := range selector.MatchAll(node) {
+		for _, attr := range linkNode.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			if u, err := s.baseURL.Parse(attr.Val); err == nil {
+				links = append(links, u.String())
+			}
+		}
+	}
+	return links
+}
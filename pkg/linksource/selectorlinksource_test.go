@@ -0,0 +1,68 @@
+package linksource
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testIndexPage = `<html><body>
+<ul class="categories"><li><a href="/recipes/desserts/">Desserts</a></li></ul>
+<ul class="recipes">
+  <li><a class="recipe" href="/recipes/apple-pie">Apple Pie</a></li>
+  <li><a class="recipe" href="https://www.example.com/recipes/pancakes">Pancakes</a></li>
+  <li><a class="recipe">No link</a></li>
+</ul>
+<a class="next" href="?page=2">Next</a>
+</body></html>`
+
+func TestSelectorLinkSource(t *testing.T) {
+	source, err := NewSelectorLinkSource(SelectorLinkSourceConfig{
+		LinkSelector:      "a.recipe",
+		NextPageSelector:  "a.next",
+		IndexPageSelector: "ul.categories a",
+		BaseURL:           "https://www.example.com/recipes/",
+	})
+	if err != nil {
+		t.Fatalf("NewSelectorLinkSource() = %v", err)
+	}
+	node, err := html.Parse(strings.NewReader(testIndexPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := source.GetLinks(node)
+	if err != nil {
+		t.Fatalf("GetLinks() = %v", err)
+	}
+	want := &LinkPage{
+		Links:      []string{"https://www.example.com/recipes/apple-pie", "https://www.example.com/recipes/pancakes"},
+		NextPage:   "https://www.example.com/recipes/?page=2",
+		IndexPages: []string{"https://www.example.com/recipes/desserts/"},
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Errorf("GetLinks() = %+v, want %+v", page, want)
+	}
+
+	// A page without recipe links is an error, the selectors are probably out of date.
+	empty, _ := html.Parse(strings.NewReader("<html><body></body></html>"))
+	if _, err := source.GetLinks(empty); err == nil {
+		t.Error("GetLinks() of a page without links = nil, want an error")
+	}
+}
+
+func TestNewSelectorLinkSourceInvalid(t *testing.T) {
+	for _, cfg := range []SelectorLinkSourceConfig{
+		{LinkSelector: ""},
+		{LinkSelector: "a[", BaseURL: "https://www.example.com/"},
+		{LinkSelector: "a", NextPageSelector: "a["},
+		{LinkSelector: "a", IndexPageSelector: "a["},
+		{LinkSelector: "a", BaseURL: "://example.com"},
+	} {
+		if _, err := NewSelectorLinkSource(cfg); err == nil {
+			t.Errorf("NewSelectorLinkSource(%+v) = nil, want an error", cfg)
+		}
+	}
+}
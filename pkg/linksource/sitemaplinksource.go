@@ -0,0 +1,185 @@
+package linksource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxSitemapSize is the most that is read of a gzipped sitemap, the size limit of the
+// sitemap protocol.
+const maxSitemapSize = 50 * 1024 * 1024
+
+// sitemapDateLayouts are the W3C datetime formats of lastmod dates.
+var sitemapDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// SitemapLinkSourceConfig defines which links of the sitemaps of a site are recipes. It is
+// read from the sitemap section of a YAML site definition.
+type SitemapLinkSourceConfig struct {
+	// Include are regular expressions of recipe links, links that match none of them are
+	// left out. If empty, every link is a recipe.
+	Include []string `yaml:"include"`
+	// Exclude are regular expressions of links that are left out, e.g. "/videos/".
+	Exclude []string `yaml:"exclude"`
+	// Sitemaps are regular expressions of the sitemaps of a sitemap index to follow, e.g.
+	// "recipe". If empty, every sitemap is followed.
+	Sitemaps []string `yaml:"sitemaps"`
+	// ChangedSince leaves out links and sitemaps last modified before it. Links without a
+	// lastmod date are kept. If zero, links are kept whenever they changed.
+	ChangedSince time.Time `yaml:"changed_since"`
+}
+
+// SitemapLinkSource finds recipe links in XML sitemaps and sitemap indexes, which may be
+// gzipped, so that any site with a sitemap can be crawled. The links of a sitemap index are
+// returned as index pages. It implements the PageLinkSource interface.
+type SitemapLinkSource struct {
+	include      []*regexp.Regexp
+	exclude      []*regexp.Regexp
+	sitemaps     []*regexp.Regexp
+	changedSince time.Time
+}
+
+// sitemapDocument is a urlset of links or a sitemapindex of sitemaps.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a link of a sitemap or a sitemap index.
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// NewSitemapLinkSource compiles the patterns of cfg into a SitemapLinkSource, or returns an
+// error if any is invalid.
+func NewSitemapLinkSource(cfg SitemapLinkSourceConfig) (*SitemapLinkSource, error) {
+	s := &SitemapLinkSource{changedSince: cfg.ChangedSince}
+	for _, patterns := range []struct {
+		name     string
+		patterns []string
+		target   *[]*regexp.Regexp
+	}{
+		{"include", cfg.Include, &s.include},
+		{"exclude", cfg.Exclude, &s.exclude},
+		{"sitemap", cfg.Sitemaps, &s.sitemaps},
+	} {
+		for _, pattern := range patterns.patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern: %w", patterns.name, err)
+			}
+			*patterns.target = append(*patterns.target, re)
+		}
+	}
+	return s, nil
+}
+
+// WithLinkPatterns returns a copy of the link source that also includes the links that
+// match any of include and leaves out the ones that match any of exclude, or an error if a
+// pattern is invalid.
+func (s SitemapLinkSource) WithLinkPatterns(include, exclude []string) (*SitemapLinkSource, error) {
+	patterns, err := NewSitemapLinkSource(SitemapLinkSourceConfig{Include: include, Exclude: exclude})
+	if err != nil {
+		return nil, err
+	}
+	s.include = append(append([]*regexp.Regexp{}, s.include...), patterns.include...)
+	s.exclude = append(append([]*regexp.Regexp{}, s.exclude...), patterns.exclude...)
+	return &s, nil
+}
+
+// WithChangedSince returns a copy of the link source that leaves out links and sitemaps
+// last modified before since.
+func (s SitemapLinkSource) WithChangedSince(since time.Time) *SitemapLinkSource {
+	s.changedSince = since
+	return &s
+}
+
+// GetLinks returns an error, as sitemaps are XML and are read with GetPageLinks.
+func (s SitemapLinkSource) GetLinks(node *html.Node) (*LinkPage, error) {
+	return nil, errors.New("sitemaps are not HTML pages")
+}
+
+// GetPageLinks returns the recipe links of a sitemap, or the sitemaps of a sitemap index as
+// index pages. A sitemap without recipe links that changed is not an error.
+func (s SitemapLinkSource) GetPageLinks(page []byte) (*LinkPage, error) {
+	// Sitemaps served as .xml.gz files are not decompressed by the HTTP client.
+	if bytes.HasPrefix(page, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress sitemap: %w", err)
+		}
+		if page, err = io.ReadAll(io.LimitReader(reader, maxSitemapSize)); err != nil {
+			return nil, fmt.Errorf("could not decompress sitemap: %w", err)
+		}
+	}
+
+	document := sitemapDocument{}
+	if err := xml.Unmarshal(page, &document); err != nil {
+		return nil, fmt.Errorf("could not parse sitemap: %w", err)
+	}
+
+	linkPage := &LinkPage{Links: make([]string, 0), IndexPages: make([]string, 0)}
+	switch document.XMLName.Local {
+	case "urlset":
+		for _, entry := range document.URLs {
+			if link := strings.TrimSpace(entry.Loc); link != "" && s.isRecipe(link) && s.changed(entry) {
+				linkPage.Links = append(linkPage.Links, link)
+			}
+		}
+	case "sitemapindex":
+		for _, entry := range document.Sitemaps {
+			if link := strings.TrimSpace(entry.Loc); link != "" && (len(s.sitemaps) == 0 || matchesAny(s.sitemaps, link)) && s.changed(entry) {
+				linkPage.IndexPages = append(linkPage.IndexPages, link)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("not a sitemap, the root element is %s", document.XMLName.Local)
+	}
+	return linkPage, nil
+}
+
+// isRecipe returns whether link is included and not excluded.
+func (s SitemapLinkSource) isRecipe(link string) bool {
+	return (len(s.include) == 0 || matchesAny(s.include, link)) && !matchesAny(s.exclude, link)
+}
+
+// changed returns whether entry was last modified on or after the date links are kept from.
+// Entries with a lastmod date that cannot be parsed are kept.
+func (s SitemapLinkSource) changed(entry sitemapEntry) bool {
+	if s.changedSince.IsZero() {
+		return true
+	}
+	lastMod := strings.TrimSpace(entry.LastMod)
+	for _, layout := range sitemapDateLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			return !t.Before(s.changedSince)
+		}
+	}
+	return true
+}
+
+// matchesAny returns whether link matches any of patterns.
+func matchesAny(patterns []*regexp.Regexp, link string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
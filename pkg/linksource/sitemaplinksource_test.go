@@ -0,0 +1,124 @@
+package linksource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://www.example.com/recipes/pie</loc><lastmod>2023-07-01</lastmod></url>
+  <url><loc> https://www.example.com/recipes/cake </loc><lastmod>2023-05-01T10:00:00+00:00</lastmod></url>
+  <url><loc>https://www.example.com/recipes/videos/pie</loc><lastmod>2023-07-01</lastmod></url>
+  <url><loc>https://www.example.com/about</loc></url>
+  <url><loc>https://www.example.com/recipes/bread</loc></url>
+</urlset>`
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://www.example.com/sitemap-recipes-1.xml</loc><lastmod>2023-07-01</lastmod></sitemap>
+  <sitemap><loc>https://www.example.com/sitemap-recipes-2.xml.gz</loc><lastmod>2023-01-01</lastmod></sitemap>
+  <sitemap><loc>https://www.example.com/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`
+
+func TestSitemapLinkSource(t *testing.T) {
+	since := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		cfg            SitemapLinkSourceConfig
+		page           string
+		wantLinks      []string
+		wantIndexPages []string
+	}{
+		{
+			"every link",
+			SitemapLinkSourceConfig{},
+			testSitemap,
+			[]string{
+				"https://www.example.com/recipes/pie", "https://www.example.com/recipes/cake",
+				"https://www.example.com/recipes/videos/pie", "https://www.example.com/about",
+				"https://www.example.com/recipes/bread",
+			},
+			[]string{},
+		},
+		{
+			"include and exclude",
+			SitemapLinkSourceConfig{Include: []string{"/recipes/"}, Exclude: []string{"/videos/"}},
+			testSitemap,
+			[]string{"https://www.example.com/recipes/pie", "https://www.example.com/recipes/cake", "https://www.example.com/recipes/bread"},
+			[]string{},
+		},
+		{
+			// Links without a lastmod date are kept.
+			"changed since",
+			SitemapLinkSourceConfig{Include: []string{"/recipes/"}, ChangedSince: since},
+			testSitemap,
+			[]string{"https://www.example.com/recipes/pie", "https://www.example.com/recipes/videos/pie", "https://www.example.com/recipes/bread"},
+			[]string{},
+		},
+		{
+			"sitemap index",
+			SitemapLinkSourceConfig{Sitemaps: []string{"recipes"}, ChangedSince: since},
+			testSitemapIndex,
+			[]string{},
+			[]string{"https://www.example.com/sitemap-recipes-1.xml"},
+		},
+	}
+	for _, test := range tests {
+		source, err := NewSitemapLinkSource(test.cfg)
+		if err != nil {
+			t.Fatalf("%s: NewSitemapLinkSource() = %v", test.name, err)
+		}
+		page, err := source.GetPageLinks([]byte(test.page))
+		if err != nil {
+			t.Errorf("%s: GetPageLinks() = %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(page.Links, test.wantLinks) || !reflect.DeepEqual(page.IndexPages, test.wantIndexPages) {
+			t.Errorf("%s: GetPageLinks() = %q, %q, want %q, %q", test.name, page.Links, page.IndexPages, test.wantLinks, test.wantIndexPages)
+		}
+	}
+}
+
+func TestSitemapLinkSourceOptions(t *testing.T) {
+	source, err := NewSitemapLinkSource(SitemapLinkSourceConfig{Exclude: []string{"/about"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := source.WithLinkPatterns([]string{"/recipes/"}, []string{"/videos/"})
+	if err != nil {
+		t.Fatalf("WithLinkPatterns() = %v", err)
+	}
+	filtered = filtered.WithChangedSince(time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC))
+
+	// Sitemaps may be gzipped.
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write([]byte(testSitemap))
+	writer.Close()
+	page, err := filtered.GetPageLinks(gzipped.Bytes())
+	if err != nil {
+		t.Fatalf("GetPageLinks() of a gzipped sitemap = %v", err)
+	}
+	if want := []string{"https://www.example.com/recipes/pie", "https://www.example.com/recipes/bread"}; !reflect.DeepEqual(page.Links, want) {
+		t.Errorf("GetPageLinks() = %q, want %q", page.Links, want)
+	}
+
+	// The options do not change the source they were added to.
+	page, err = source.GetPageLinks([]byte(testSitemap))
+	if err != nil || len(page.Links) != 4 {
+		t.Errorf("GetPageLinks() of the unfiltered source = %q, %v, want 4 links", page.Links, err)
+	}
+
+	if _, err := source.WithLinkPatterns([]string{"("}, nil); err == nil {
+		t.Error("WithLinkPatterns() of an invalid pattern = nil, want an error")
+	}
+	for _, page := range []string{"<html><body>not a sitemap</body></html>", "not xml"} {
+		if _, err := source.GetPageLinks([]byte(page)); err == nil {
+			t.Errorf("GetPageLinks(%q) = nil, want an error", page)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package linksource
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+const (
+	wikibooksLinkSelector        = "div#mw-pages a[href][title^='Cookbook:']"
+	wikibooksNextPageSelector    = "div#mw-pages a[href]"
+	wikibooksSubcategorySelector = "div#mw-subcategories a[href][title^='Category:']"
+	wikibooksBaseLink            = "https://en.wikibooks.org"
+	wikibooksNextPageText        = "next page"
+)
+
+// WikibooksLinkSource finds the recipes of the Wikibooks Cookbook by walking its category
+// pages, e.g. https://en.wikibooks.org/wiki/Category:Recipes, including subcategories.
+type WikibooksLinkSource struct {
+	linkSelector        css.Selector
+	nextPageSelector    css.Selector
+	subcategorySelector css.Selector
+}
+
+func NewWikibooksLinkSource() *WikibooksLinkSource {
+	return &WikibooksLinkSource{
+		linkSelector:        css.MustCompile(wikibooksLinkSelector),
+		nextPageSelector:    css.MustCompile(wikibooksNextPageSelector),
+		subcategorySelector: css.MustCompile(wikibooksSubcategorySelector),
+	}
+}
+
+func (w WikibooksLinkSource) GetLinks(node *html.Node) (*LinkPage, error) {
+	linkNodes := w.linkSelector.MatchAll(node)
+	subcategoryNodes := w.subcategorySelector.MatchAll(node)
+	if len(linkNodes) == 0 && len(subcategoryNodes) == 0 {
+		return nil, fmt.Errorf("did not find any recipe or subcategory links")
+	}
+
+	links := make([]string, 0, len(linkNodes))
+	for _, linkNode := range linkNodes {
+		if link := wikibooksLink(linkNode); link != "" {
+			links = append(links, link)
+		}
+	}
+
+	subcategories := make([]string, 0, len(subcategoryNodes))
+	for _, subcategoryNode := range subcategoryNodes {
+		if link := wikibooksLink(subcategoryNode); link != "" {
+			subcategories = append(subcategories, link)
+		}
+	}
+
+	// The pages of a category are listed 200 at a time, the "next page" link leads on.
+	nextPage := ""
+	for _, nextPageNode := range w.nextPageSelector.MatchAll(node) {
+		if nextPageNode.FirstChild != nil && strings.TrimSpace(nextPageNode.FirstChild.Data) == wikibooksNextPageText {
+			nextPage = wikibooksLink(nextPageNode)
+			break
+		}
+	}
+
+	return &LinkPage{
+		Links:      links,
+		NextPage:   nextPage,
+		IndexPages: subcategories,
+	}, nil
+}
+
+// wikibooksLink returns the absolute link of an anchor. Links to /w/index.php?title=X are
+// rewritten to /wiki/X, as robots.txt disallows crawling /w/.
+func wikibooksLink(node *html.Node) string {
+	href := ""
+	for _, attr := range node.Attr {
+		if attr.Key == "href" {
+			href = attr.Val
+		}
+	}
+
+	base, _ := url.Parse(wikibooksBaseLink)
+	u, err := base.Parse(href)
+	if err != nil || href == "" {
+		return ""
+	}
+
+	if u.Path == "/w/index.php" {
+		query := u.Query()
+		if title := query.Get("title"); title != "" {
+			query.Del("title")
+			u.Path = "/wiki/" + title
+			u.RawQuery = query.Encode()
+		}
+	}
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,46 @@
+package linksource
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testCategoryPage = `<html><body>
+<div id="mw-subcategories">
+  <a href="/wiki/Category:Pie_recipes" title="Category:Pie recipes">Pie recipes</a>
+</div>
+<div id="mw-pages">
+  <a href="/w/index.php?title=Category:Recipes&amp;pagefrom=Bread#mw-pages" title="Category:Recipes">previous page</a>
+  <a href="/w/index.php?title=Category:Recipes&amp;pagefrom=Pancakes#mw-pages" title="Category:Recipes">next page</a>
+  <a href="/wiki/Cookbook:Apple_Pie" title="Cookbook:Apple Pie">Apple Pie</a>
+  <a href="/wiki/Cookbook:Bread#Variations" title="Cookbook:Bread">Bread</a>
+  <a href="/wiki/Help:Contents" title="Help:Contents">Help</a>
+</div>
+</body></html>`
+
+func TestWikibooksLinkSource(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(testCategoryPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewWikibooksLinkSource().GetLinks(node)
+	if err != nil {
+		t.Fatalf("GetLinks() = %v", err)
+	}
+	want := &LinkPage{
+		Links:      []string{"https://en.wikibooks.org/wiki/Cookbook:Apple_Pie", "https://en.wikibooks.org/wiki/Cookbook:Bread"},
+		NextPage:   "https://en.wikibooks.org/wiki/Category:Recipes?pagefrom=Pancakes",
+		IndexPages: []string{"https://en.wikibooks.org/wiki/Category:Pie_recipes"},
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Errorf("GetLinks() = %+v, want %+v", page, want)
+	}
+
+	empty, _ := html.Parse(strings.NewReader(`<html><body><div id="mw-pages"></div></body></html>`))
+	if _, err := NewWikibooksLinkSource().GetLinks(empty); err == nil {
+		t.Error("GetLinks() of a page without recipes or subcategories = nil, want an error")
+	}
+}
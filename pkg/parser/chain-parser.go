@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"golang.org/x/net/html"
+)
+
+// ChainParser tries a list of parsers in order, e.g. a site specific parser followed by
+// generic structured data parsers. The next parser is only tried if the previous one
+// returned ErrParseFailed. It implements the Parser interface.
+type ChainParser struct {
+	parsers []Parser
+}
+
+// NewChainParser creates a new ChainParser that tries parsers in order.
+func NewChainParser(parsers ...Parser) *ChainParser {
+	return &ChainParser{parsers: parsers}
+}
+
+// ParseRecipe returns the recipe of the first parser that parses node with the default
+// field policy.
+func (p *ChainParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
+	return parseDefault(p, node)
+}
+
+// ParseDiagnostics returns the recipe and report of the first parser that parses node, with
+// the report of the first parser as its Primary if they differ. If every parser fails to
+// parse a field, the error and report of the first parser are returned, as it is the most
+// specific one. Any other error is returned straight away.
+func (p *ChainParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	var firstErr error
+	var firstDiagnostics *Diagnostics
+	for _, parser := range p.parsers {
+		rawRecipe, diagnostics, err := Parse(parser, node, policy)
+		if err == nil {
+			if firstDiagnostics != nil {
+				diagnostics.Primary = firstDiagnostics
+			}
+			return rawRecipe, diagnostics, nil
+		}
+
+		var parseErr ErrParseFailed
+		if !errors.As(err, &parseErr) {
+			return nil, diagnostics, err
+		}
+		if firstErr == nil {
+			firstErr = err
+			firstDiagnostics = diagnostics
+		}
+	}
+	if firstErr == nil {
+		diagnostics := NewDiagnostics()
+		diagnostics.missingAll("no parsers")
+		return nil, diagnostics, ErrParseFailed{Field: "recipe"}
+	}
+	return nil, firstDiagnostics, firstErr
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"golang.org/x/net/html"
+)
+
+// stubParser returns its recipe, or fails to parse field if it has none.
+type stubParser struct {
+	rawRecipe *recipe.RawRecipe
+	field     string
+}
+
+func (p stubParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
+	if p.rawRecipe == nil {
+		return nil, ErrParseFailed{Field: p.field}
+	}
+	return p.rawRecipe, nil
+}
+
+func TestChainParserPrimaryReport(t *testing.T) {
+	rawRecipe := &recipe.RawRecipe{Name: "Pancakes", IngredientDescriptions: []string{"1 cup flour"}, Steps: []string{"Mix."}}
+	tests := []struct {
+		name        string
+		parsers     []Parser
+		wantErr     bool
+		wantPrimary FieldStatus
+	}{
+		{"first parser", []Parser{stubParser{rawRecipe: rawRecipe}, stubParser{field: FieldName}}, false, FieldFound},
+		{"fallback parser", []Parser{stubParser{field: FieldName}, stubParser{rawRecipe: rawRecipe}}, false, FieldMissing},
+		{"no parser", []Parser{stubParser{field: FieldName}, stubParser{field: FieldSteps}}, true, FieldMissing},
+	}
+	for _, test := range tests {
+		got, diagnostics, err := NewChainParser(test.parsers...).ParseDiagnostics(&html.Node{}, DefaultFieldPolicy())
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: ParseDiagnostics() error = %v, want error %t", test.name, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && (got != rawRecipe || diagnostics.Status(FieldName) != FieldFound) {
+			t.Errorf("%s: ParseDiagnostics() did not return the recipe of the parser that parsed it", test.name)
+		}
+		if status := diagnostics.PrimaryReport().Status(FieldName); status != test.wantPrimary {
+			t.Errorf("%s: primary status of %s = %s, want %s", test.name, FieldName, status, test.wantPrimary)
+		}
+	}
+}
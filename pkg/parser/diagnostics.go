@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"golang.org/x/net/html"
+)
+
+// The fields of a recipe that parsers report on.
+const (
+	FieldName         = "name"
+	FieldDescription  = "description"
+	FieldIngredients  = "ingredients"
+	FieldSteps        = "steps"
+	FieldEquipment    = "equipment"
+	FieldTags         = "tags"
+	FieldImage        = "image"
+	FieldVideo        = "video"
+	FieldDifficulty   = "difficulty"
+	FieldTotalTime    = "total_time"
+	FieldPrepTime     = "prep_time"
+	FieldCookTime     = "cook_time"
+	FieldInactiveTime = "inactive_time"
+	FieldServings     = "servings"
+	FieldCalories     = "calories"
+	FieldNutrition    = "nutrition"
+	FieldAuthor       = "author"
+	FieldRating       = "rating"
+	FieldPublished    = "published"
+	FieldUpdated      = "updated"
+)
+
+// Fields lists every field parsers report on, in the order of a recipe.
+var Fields = []string{
+	FieldName, FieldDescription, FieldIngredients, FieldSteps, FieldEquipment, FieldTags, FieldImage,
+	FieldVideo, FieldDifficulty, FieldTotalTime, FieldPrepTime, FieldCookTime, FieldInactiveTime,
+	FieldServings, FieldCalories, FieldNutrition, FieldAuthor, FieldRating, FieldPublished, FieldUpdated,
+}
+
+// FieldStatus is how a parser filled in a field of a recipe.
+type FieldStatus string
+
+const (
+	// FieldFound fields were parsed from the page.
+	FieldFound FieldStatus = "found"
+	// FieldMissing fields are not on the page, and are left empty.
+	FieldMissing FieldStatus = "missing"
+	// FieldDefaulted fields are not on the page or could not be parsed, and are given a
+	// default value, e.g. a total time of the prep and cook times.
+	FieldDefaulted FieldStatus = "defaulted"
+)
+
+// FieldReport is the status of a single field, and why it was not found.
+type FieldReport struct {
+	Field  string      `json:"field" yaml:"field"`
+	Status FieldStatus `json:"status" yaml:"status"`
+	Reason string      `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+func (r FieldReport) String() string {
+	if r.Reason == "" {
+		return r.Field + ": " + string(r.Status)
+	}
+	return r.Field + ": " + string(r.Status) + " (" + r.Reason + ")"
+}
+
+// Diagnostics reports how a parser filled in every field of a recipe, and the warnings
+// it had on the way.
+type Diagnostics struct {
+	Fields   []FieldReport  `json:"fields" yaml:"fields"`
+	Warnings []ParseWarning `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	// Primary is the report of the first parser of a ChainParser, if a later parser
+	// parsed the recipe. Drift is detected on it, as the fallback hides a site specific
+	// parser that stopped finding a field.
+	Primary *Diagnostics `json:"-" yaml:"-"`
+}
+
+// NewDiagnostics creates an empty report.
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{Fields: make([]FieldReport, 0, len(Fields))}
+}
+
+// Found reports that field was parsed from the page.
+func (d *Diagnostics) Found(field string) {
+	d.set(FieldReport{Field: field, Status: FieldFound})
+}
+
+// Missing reports that field is not on the page, and why.
+func (d *Diagnostics) Missing(field, reason string) {
+	d.set(FieldReport{Field: field, Status: FieldMissing, Reason: reason})
+}
+
+// Defaulted reports that field was given a default value, and why.
+func (d *Diagnostics) Defaulted(field, reason string) {
+	d.set(FieldReport{Field: field, Status: FieldDefaulted, Reason: reason})
+}
+
+// foundIf reports field as found if ok, or as missing for reason otherwise.
+func (d *Diagnostics) foundIf(ok bool, field, reason string) {
+	if ok {
+		d.Found(field)
+	} else {
+		d.Missing(field, reason)
+	}
+}
+
+// missingAll reports every field as missing for reason, e.g. when there is no recipe.
+func (d *Diagnostics) missingAll(reason string) {
+	for _, field := range Fields {
+		d.Missing(field, reason)
+	}
+}
+
+// set records the report of a field, replacing an earlier report of the same field.
+func (d *Diagnostics) set(report FieldReport) {
+	for i := range d.Fields {
+		if d.Fields[i].Field == report.Field {
+			d.Fields[i] = report
+			return
+		}
+	}
+	d.Fields = append(d.Fields, report)
+}
+
+// Warn adds warnings to the report.
+func (d *Diagnostics) Warn(warnings ...ParseWarning) {
+	d.Warnings = append(d.Warnings, warnings...)
+}
+
+// PrimaryReport returns the report of the first parser of the chains the recipe was
+// parsed with, or the report itself if no fallback parser was used.
+func (d *Diagnostics) PrimaryReport() *Diagnostics {
+	for d.Primary != nil {
+		d = d.Primary
+	}
+	return d
+}
+
+// Status returns the status of field. Fields that were not reported are missing.
+func (d *Diagnostics) Status(field string) FieldStatus {
+	if report, ok := d.Report(field); ok {
+		return report.Status
+	}
+	return FieldMissing
+}
+
+// Report returns the report of field, if there is one.
+func (d *Diagnostics) Report(field string) (FieldReport, bool) {
+	for _, report := range d.Fields {
+		if report.Field == field {
+			return report, true
+		}
+	}
+	return FieldReport{}, false
+}
+
+// Issues lists the fields that were not found and the warnings, for logs.
+func (d *Diagnostics) Issues() []string {
+	issues := make([]string, 0)
+	for _, report := range d.Fields {
+		if report.Status != FieldFound {
+			issues = append(issues, report.String())
+		}
+	}
+	for _, warning := range d.Warnings {
+		issues = append(issues, warning.String())
+	}
+	return issues
+}
+
+// Check returns ErrParseFailed for the first field policy requires that was not found.
+func (d *Diagnostics) Check(policy FieldPolicy) error {
+	for _, field := range policy.Required {
+		if d.Status(field) != FieldFound {
+			return ErrParseFailed{Field: field}
+		}
+	}
+	return nil
+}
+
+// FieldPolicy decides which fields a recipe is not kept without. A recipe that misses any
+// other field is kept with what could be parsed.
+type FieldPolicy struct {
+	Required []string `yaml:"required_fields"`
+}
+
+// DefaultFieldPolicy requires the fields no recipe is of use without.
+func DefaultFieldPolicy() FieldPolicy {
+	return FieldPolicy{Required: []string{FieldName, FieldIngredients, FieldSteps}}
+}
+
+// Validate returns an error if the policy requires a field parsers do not report on.
+func (p FieldPolicy) Validate() error {
+	for _, field := range p.Required {
+		known := false
+		for _, name := range Fields {
+			known = known || name == field
+		}
+		if !known {
+			return fmt.Errorf("unknown required field %q, fields are %s", field, strings.Join(Fields, ", "))
+		}
+	}
+	return nil
+}
+
+// with returns a policy that also requires fields.
+func (p FieldPolicy) with(fields ...string) FieldPolicy {
+	if len(fields) == 0 {
+		return p
+	}
+	required := make([]string, 0, len(p.Required)+len(fields))
+	return FieldPolicy{Required: append(append(required, p.Required...), fields...)}
+}
+
+// DiagnosticParser is a Parser that reports how it filled in every field of a recipe. It
+// keeps what it could parse of a recipe unless a field policy requires is not found.
+type DiagnosticParser interface {
+	Parser
+	ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error)
+}
+
+// Parse parses node with p and reports how every field was filled in. The recipe of a
+// parser that is not a DiagnosticParser is reported on from the fields it has.
+func Parse(p Parser, node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	if dp, ok := p.(DiagnosticParser); ok {
+		return dp.ParseDiagnostics(node, policy)
+	}
+
+	diagnostics := NewDiagnostics()
+	rawRecipe, err := p.ParseRecipe(node)
+	if err != nil {
+		var parseErr ErrParseFailed
+		if errors.As(err, &parseErr) {
+			diagnostics.Missing(parseErr.Field, err.Error())
+		}
+		return nil, diagnostics, err
+	}
+
+	diagnostics.foundIf(rawRecipe.Name != "", FieldName, "empty")
+	diagnostics.foundIf(rawRecipe.Description != "", FieldDescription, "empty")
+	diagnostics.foundIf(len(rawRecipe.IngredientDescriptions) > 0, FieldIngredients, "empty")
+	diagnostics.foundIf(len(rawRecipe.Steps) > 0, FieldSteps, "empty")
+	diagnostics.foundIf(len(rawRecipe.Metadata.Tags) > 0, FieldTags, "empty")
+	diagnostics.foundIf(rawRecipe.Metadata.ImageURL != "", FieldImage, "empty")
+	if err := diagnostics.Check(policy); err != nil {
+		return nil, diagnostics, err
+	}
+	return rawRecipe, diagnostics, nil
+}
+
+// parseDefault parses node with the default field policy, for the ParseRecipe method of
+// DiagnosticParsers.
+func parseDefault(p DiagnosticParser, node *html.Node) (*recipe.RawRecipe, error) {
+	rawRecipe, _, err := p.ParseDiagnostics(node, DefaultFieldPolicy())
+	return rawRecipe, err
+}
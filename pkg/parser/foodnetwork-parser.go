@@ -1,8 +1,7 @@
 package parser
 
 import (
-	"regexp"
-	"strconv"
+	"fmt"
 	"strings"
 
 	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
@@ -16,25 +15,38 @@ const (
 	foodNetworkDescriptionSelector = "div.o-AssetDescription__a-Description"
 	foodnetworkIngredientsSelector = "p:not(.o-Ingredients__a-Ingredient--SelectAll) span.o-Ingredients__a-Ingredient--CheckboxLabel"
 	foodnetworkStepsSelector       = "li.o-Method__m-Step"
+	// Headings of ingredient groups and step sections, e.g. "For the crust:"
+	foodnetworkIngredientHeadingSelector = ".o-Ingredients__a-SubHeadline"
+	foodnetworkStepHeadingSelector       = ".o-Method__a-SubHeadline"
 
 	foodnetworkLevelSelector = "div.recipeInfo ul.o-RecipeInfo__m-Level li"
 	foodnetworkTimeSelector  = "div.recipeInfo ul.o-RecipeInfo__m-Time li"
 	foodnetworkYieldSelector = "div.recipeInfo ul.o-RecipeInfo__m-Yield li"
 	foodnetworkTagSelector   = "div.m-TagList a.o-Capsule__a-Tag"
+	// The byline, and the rating and review count of the reviews. The rating is the title of
+	// the stars, e.g. "4.5 out of 5."
+	foodnetworkAuthorSelector      = ".o-Attribution__a-Name"
+	foodnetworkRatingSelector      = ".gig-rating-stars"
+	foodnetworkReviewCountSelector = ".gig-rating-ratingsum"
+	// The nutrition panel lists its facts as dt and dd elements.
+	foodnetworkNutritionSelector       = "div.m-NutritionTable"
+	foodnetworkNutritionLabelSelector  = ".m-NutritionTable__a-Headline"
+	foodnetworkNutritionAmountSelector = ".m-NutritionTable__a-Description"
 	// Match either normal image or video thumbnail
-	foodnetworkImageSelector = "div.m-RecipeMedia__m-MediaBlock img, img.kdp-poster__image"
+	foodnetworkImageSelector       = "div.m-RecipeMedia__m-MediaBlock img, img.kdp-poster__image"
+	foodnetworkVideoPosterSelector = "img.kdp-poster__image"
+	// The image shown for recipes without one.
+	foodnetworkPlaceholderImage = "1474463768097.jpeg"
 )
 
 // Some helper selectors, REs and map.
 var (
-	foodNetworkTimeRE   = regexp.MustCompile(`((\d+) hr)? ?((\d+) min)?$`)
 	foodnetworkLevelMap = map[string]recipe.RecipeDifficulty{
 		"Easy":         recipe.Easy,
 		"Medium":       recipe.Medium,
 		"Intermediate": recipe.Medium,
 		"Hard":         recipe.Hard,
 	}
-	foodnetworkYieldRE = regexp.MustCompile(`((\d+)( ?- ?|( to )))?(\d+) servings?`)
 	// Match either headline or description spans (click tracking is also a span there).
 	foodNetworkMetadataSpanSelector = css.MustCompile("span.o-RecipeInfo__a-Headline, span.o-RecipeInfo__a-Description")
 )
@@ -42,151 +54,104 @@ var (
 // FoodnetworkParser is a parser for the Food Network website. It implements the Parser interface.
 // It takes a HTML node and parses it into a RawRecipe struct.
 type FoodnetworkParser struct {
-	nameSelector        css.Selector
-	descriptionSelector css.Selector
-	ingredientSelector  css.Selector
-	stepsSelector       css.Selector
-	levelSelector       css.Selector
-	timeSelector        css.Selector
-	yieldSelector       css.Selector
-	tagSelector         css.Selector
-	imageSelector       css.Selector
+	nameSelector              css.Selector
+	descriptionSelector       css.Selector
+	ingredientSelector        css.Selector
+	stepsSelector             css.Selector
+	ingredientHeadingSelector css.Selector
+	stepHeadingSelector       css.Selector
+	levelSelector             css.Selector
+	timeSelector              css.Selector
+	yieldSelector             css.Selector
+	tagSelector               css.Selector
+	imageSelector             css.Selector
+	videoPosterSelector       css.Selector
+	authorSelector            css.Selector
+	ratingSelector            css.Selector
+	reviewCountSelector       css.Selector
+	nutritionSelector         css.Selector
+	nutritionLabelSelector    css.Selector
+	nutritionAmountSelector   css.Selector
 }
 
 // NewFoodnetworkParser creates a new FoodnetworkParser.
 func NewFoodnetworkParser() *FoodnetworkParser {
 	return &FoodnetworkParser{
-		nameSelector:        css.MustCompile(foodnetworkNameSelector),
-		descriptionSelector: css.MustCompile(foodNetworkDescriptionSelector),
-		ingredientSelector:  css.MustCompile(foodnetworkIngredientsSelector),
-		stepsSelector:       css.MustCompile(foodnetworkStepsSelector),
-		levelSelector:       css.MustCompile(foodnetworkLevelSelector),
-		timeSelector:        css.MustCompile(foodnetworkTimeSelector),
-		yieldSelector:       css.MustCompile(foodnetworkYieldSelector),
-		tagSelector:         css.MustCompile(foodnetworkTagSelector),
-		imageSelector:       css.MustCompile(foodnetworkImageSelector),
+		nameSelector:              css.MustCompile(foodnetworkNameSelector),
+		descriptionSelector:       css.MustCompile(foodNetworkDescriptionSelector),
+		ingredientSelector:        css.MustCompile(foodnetworkIngredientsSelector),
+		stepsSelector:             css.MustCompile(foodnetworkStepsSelector),
+		ingredientHeadingSelector: css.MustCompile(foodnetworkIngredientHeadingSelector),
+		stepHeadingSelector:       css.MustCompile(foodnetworkStepHeadingSelector),
+		levelSelector:             css.MustCompile(foodnetworkLevelSelector),
+		timeSelector:              css.MustCompile(foodnetworkTimeSelector),
+		yieldSelector:             css.MustCompile(foodnetworkYieldSelector),
+		tagSelector:               css.MustCompile(foodnetworkTagSelector),
+		imageSelector:             css.MustCompile(foodnetworkImageSelector),
+		videoPosterSelector:       css.MustCompile(foodnetworkVideoPosterSelector),
+		authorSelector:            css.MustCompile(foodnetworkAuthorSelector),
+		ratingSelector:            css.MustCompile(foodnetworkRatingSelector),
+		reviewCountSelector:       css.MustCompile(foodnetworkReviewCountSelector),
+		nutritionSelector:         css.MustCompile(foodnetworkNutritionSelector),
+		nutritionLabelSelector:    css.MustCompile(foodnetworkNutritionLabelSelector),
+		nutritionAmountSelector:   css.MustCompile(foodnetworkNutritionAmountSelector),
 	}
 }
 
 // ParseRecipe parses a HTML node into a RawRecipe struct, or returns an error if it fails.
 func (p *FoodnetworkParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
-	name, err := p.parseSingleText(p.nameSelector, node, "name")
-	if err != nil {
-		return nil, err
-	}
-
-	description, err := p.parseSingleText(p.descriptionSelector, node, "description")
-	if err != nil {
-		// Some recipes don't have a description
-		description = ""
-	}
-
-	ingredients, err := p.parseListText(p.ingredientSelector, node, "ingredients")
-	if err != nil {
-		return nil, err
-	}
-
-	steps, err := p.parseListText(p.stepsSelector, node, "steps")
-	if err != nil {
-		return nil, err
-	}
-
-	metadata, err := p.parseRecipeMetadata(node)
-	if err != nil {
-		return nil, err
-	}
-
-	return &recipe.RawRecipe{
-		Name:                   name,
-		Description:            description,
-		IngredientDescriptions: ingredients,
-		Steps:                  steps,
-		Metadata:               metadata,
-	}, nil
+	return parseDefault(p, node)
 }
 
-// parseSingleText parses a single text field from a HTML node that gets selected.
-func (p *FoodnetworkParser) parseSingleText(selector css.Selector, node *html.Node, name string) (string, error) {
-	nodes := selector.MatchAll(node)
-	if len(nodes) < 1 {
-		return "", ErrParseFailed{Field: name}
-	}
-
-	return nodes[0].FirstChild.Data, nil
-}
+// ParseDiagnostics parses a HTML node into a RawRecipe struct and reports how every field
+// was filled in. It returns an error if a field policy requires is not found.
+func (p *FoodnetworkParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	diagnostics := NewDiagnostics()
 
-// parseListText parses a list of text fields of the direct children from a HTML node that gets selected.
-func (p *FoodnetworkParser) parseListText(selector css.Selector, node *html.Node, name string) ([]string, error) {
-	nodes := selector.MatchAll(node)
-	if len(nodes) < 1 {
-		return nil, ErrParseFailed{Field: name}
+	rawRecipe := &recipe.RawRecipe{
+		Name: p.parseSingleText(p.nameSelector, node, FieldName, diagnostics),
+		// Some recipes don't have a description
+		Description: p.parseSingleText(p.descriptionSelector, node, FieldDescription, diagnostics),
+		Metadata:    p.parseRecipeMetadata(node, diagnostics),
 	}
-
-	// pre-allocate the slice
-	text := make([]string, len(nodes))
-	for i, node := range nodes {
-		text[i] = node.FirstChild.Data
+	rawRecipe.SetIngredientGroups(ingredientGroups(
+		p.parseGroupedText(p.ingredientSelector, p.ingredientHeadingSelector, isGroupHeading, node, FieldIngredients, diagnostics)))
+	rawRecipe.SetStepSections(stepSections(
+		p.parseGroupedText(p.stepsSelector, p.stepHeadingSelector, nil, node, FieldSteps, diagnostics)))
+	// The pages have no equipment section. Equipment is found in the steps later on, so the
+	// field is left unreported rather than missing.
+
+	if err := diagnostics.Check(policy); err != nil {
+		return nil, diagnostics, err
 	}
-
-	return text, nil
+	return rawRecipe, diagnostics, nil
 }
 
-// parseTimeString returns a x hr y min string as minutes.
-func (p *FoodnetworkParser) parseTimeString(text string) (int, error) {
-	matches := foodNetworkTimeRE.FindStringSubmatch(text)
-	if matches == nil {
-		return 0, ErrParseFailed{Field: "time string"}
-	}
-
-	// group 2 is hours, group 4 is minutes
-	hourStr := matches[2]
-	minuteStr := matches[4]
-
-	// if the strings are empty (no match), set them to 0
-	if hourStr == "" {
-		hourStr = "0"
-	}
-
-	if minuteStr == "" {
-		minuteStr = "0"
-	}
-
-	// know they are ints
-	hours, _ := strconv.Atoi(hourStr)
-	minutes, _ := strconv.Atoi(minuteStr)
-
-	if hours <= 0 && minutes <= 0 {
-		return 0, ErrParseFailed{Field: "time string"}
-	}
-
-	// return the minutes
-	return hours*60 + minutes, nil
+// parseSingleText parses the text of the first element that gets selected, including the
+// text of any markup inside it. Selected elements without text are reported as warnings.
+func (p *FoodnetworkParser) parseSingleText(selector css.Selector, node *html.Node, field string, diagnostics *Diagnostics) string {
+	text, warnings := SelectText(selector, node, field, TextOptions{})
+	diagnostics.Warn(warnings...)
+	diagnostics.foundIf(text != "", field, "no element with text")
+	return text
 }
 
-// parseServings parses a string like "4 to 6 servings" into a ServingRange struct.
-func (p *FoodnetworkParser) parseServings(text string) recipe.ServingRange {
-	matches := foodnetworkYieldRE.FindStringSubmatch(text)
-	if matches == nil {
-		// if there is no match, just return the text as the alternative
-		return recipe.ServingRange{Alternative: text}
-	}
-
-	// group 2 is min, group 5 is max. If group 2 is empty, set it to group 5
-	minStr := matches[2]
-	maxStr := matches[5]
-	if minStr == "" {
-		minStr = maxStr
-	}
-
-	// know they are ints
-	min, _ := strconv.Atoi(minStr)
-	max, _ := strconv.Atoi(maxStr)
-	return recipe.ServingRange{Min: min, Max: max}
+// parseGroupedText parses the text of every element that gets selected, including the text
+// of any markup inside them, grouped under the headings before them. Selected elements
+// without text are left out and reported as warnings.
+func (p *FoodnetworkParser) parseGroupedText(selector, heading css.Selector, isHeading func(string) bool, node *html.Node, field string, diagnostics *Diagnostics) []TextGroup {
+	groups, warnings := SelectGroups(selector, heading, isHeading, node, field, TextOptions{})
+	diagnostics.Warn(warnings...)
+	diagnostics.foundIf(len(groups) > 0, field, "no element with text")
+	return groups
 }
 
-// parseRecipeMetadata parses the metadata of a recipe.
-func (p *FoodnetworkParser) parseRecipeMetadata(node *html.Node) (recipe.RecipeMetadata, error) {
+// parseRecipeMetadata parses the metadata of a recipe. Metadata that cannot be parsed is
+// left at its default value and reported, so it does not cost the whole recipe.
+func (p *FoodnetworkParser) parseRecipeMetadata(node *html.Node, diagnostics *Diagnostics) recipe.RecipeMetadata {
 	metadata := recipe.RecipeMetadata{EstimatedCalories: -1}
+	facts := selectNutritionFacts(p.nutritionSelector, p.nutritionLabelSelector, p.nutritionAmountSelector, node)
+	parseNutrition(facts, "no nutrition panel", &metadata, diagnostics)
 
 	// parse the difficulty, time, and yield. The place where certain metadata is located is not consistent,
 	// e.g. total time is sometimes in Level.
@@ -202,62 +167,101 @@ func (p *FoodnetworkParser) parseRecipeMetadata(node *html.Node) (recipe.RecipeM
 		if len(spans) < 2 {
 			continue
 		}
-		headline, text := spans[0].FirstChild.Data, spans[1].FirstChild.Data
+		headline, text := ExtractText(spans[0], TextOptions{}), ExtractText(spans[1], TextOptions{})
 
 		switch headline {
 		case "Level:":
-			metadata.Difficulty = foodnetworkLevelMap[text]
-		case "Total:":
-			time, err := p.parseTimeString(text)
-			if err != nil {
-				return metadata, err
+			difficulty, ok := foodnetworkLevelMap[text]
+			if !ok {
+				diagnostics.Defaulted(FieldDifficulty, fmt.Sprintf("unknown level %q", text))
+				continue
 			}
-			metadata.MinutesTotal = time
-
+			metadata.Difficulty = difficulty
+			diagnostics.Found(FieldDifficulty)
+		case "Total:":
+			metadata.MinutesTotal = parseTimeText(text, FieldTotalTime, &metadata, diagnostics)
 		case "Prep:":
-			time, err := p.parseTimeString(text)
-			if err != nil {
-				return metadata, err
-			}
-			metadata.MinutesToPrep = time
-
+			metadata.MinutesToPrep = parseTimeText(text, FieldPrepTime, &metadata, diagnostics)
 		case "Cook:", "Active:":
-			time, err := p.parseTimeString(text)
-			if err != nil {
-				return metadata, err
-			}
-			metadata.MinutesToCook = time
-
+			metadata.MinutesToCook = parseTimeText(text, FieldCookTime, &metadata, diagnostics)
+		case "Inactive:":
+			// Other times may have mentioned a rest, e.g. "1 hr plus overnight", already.
+			addInactiveTime(&metadata, parseTimeText(text, FieldInactiveTime, &metadata, diagnostics), diagnostics)
 		case "Yield:":
-			metadata.Servings = p.parseServings(text)
+			metadata.Servings = parseServingsText(text)
+			if metadata.Servings.Max == 0 {
+				diagnostics.Defaulted(FieldServings, fmt.Sprintf("no servings in %q, keeping the text", text))
+			} else {
+				diagnostics.Found(FieldServings)
+			}
 
 		default:
 			// Skip unknown headlines
 		}
 	}
+	for _, field := range []string{FieldDifficulty, FieldTotalTime, FieldPrepTime, FieldCookTime, FieldInactiveTime, FieldServings} {
+		if _, ok := diagnostics.Report(field); !ok {
+			diagnostics.Missing(field, "no headline")
+		}
+	}
 
 	// Tags are some stuff at the end. Nice to have for extra data about the recipe.
-	tagNodes := p.tagSelector.MatchAll(node)
-	tags := make([]string, len(tagNodes))
-	for i, node := range tagNodes {
-		tags[i] = node.FirstChild.Data
-	}
+	tags, warnings := SelectTexts(p.tagSelector, node, FieldTags, TextOptions{})
+	diagnostics.Warn(warnings...)
+	diagnostics.foundIf(len(tags) > 0, FieldTags, "no tags")
 	metadata.Tags = tags
 
-	// Parse the image and alt text.
-	imageNode := p.imageSelector.MatchFirst(node)
-	if imageNode != nil {
-		for _, attr := range imageNode.Attr {
-			if attr.Key == "src" {
-				if !strings.HasSuffix(attr.Val, "1474463768097.jpeg") {
-					metadata.ImageURL = "https:" + attr.Val
-				}
-			}
-			if attr.Key == "alt" {
-				metadata.ImageAlt = attr.Val
+	// Parse the images of the recipe and its steps, and the video. Recipes with a video show
+	// its thumbnail instead of an image.
+	placeholder := false
+	for _, image := range selectImages(p.imageSelector, node) {
+		if strings.HasSuffix(image.URL, foodnetworkPlaceholderImage) {
+			placeholder = true
+			continue
+		}
+		metadata.AddImages(image)
+	}
+	metadata.AddImages(selectStepImages(p.stepsSelector, node)...)
+	switch {
+	case metadata.ImageURL != "":
+		diagnostics.Found(FieldImage)
+	case placeholder:
+		diagnostics.Missing(FieldImage, "placeholder image")
+	default:
+		diagnostics.Missing(FieldImage, "no image")
+	}
+
+	metadata.Video = parseMetaVideo(node)
+	if poster := p.videoPosterSelector.MatchFirst(node); poster != nil {
+		if image, ok := imageFromNode(poster); ok {
+			if metadata.Video == nil {
+				metadata.Video = &recipe.Video{}
 			}
+			metadata.Video.ThumbnailURL = image.URL
+		}
+	}
+	diagnostics.foundIf(metadata.Video != nil, FieldVideo, "no video")
+
+	// The byline, the rating of the reviews and the dates in the meta tags of the page.
+	metadata.Author = p.parseSingleText(p.authorSelector, node, FieldAuthor, diagnostics)
+	diagnostics.Missing(FieldRating, "no rating")
+	if ratingNode := p.ratingSelector.MatchFirst(node); ratingNode != nil {
+		text := attr(ratingNode, "title")
+		if text == "" {
+			text = ExtractText(ratingNode, TextOptions{})
+		}
+		if rating, ok := parseRating(text); ok {
+			metadata.Rating = rating
+			diagnostics.Found(FieldRating)
+		} else {
+			diagnostics.Defaulted(FieldRating, fmt.Sprintf("no rating in %q", text))
 		}
 	}
+	if reviewNode := p.reviewCountSelector.MatchFirst(node); reviewNode != nil {
+		metadata.ReviewCount, _ = parseCount(ExtractText(reviewNode, TextOptions{}))
+	}
+	parseMetaDates(node, &metadata, diagnostics)
 
-	return metadata, nil
+	metadata.ResolveURLs(pageBaseURL(node))
+	return metadata
 }
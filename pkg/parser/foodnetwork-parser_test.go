@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// foodnetworkTestPage returns a Food Network page with the times of the recipe info.
+func foodnetworkTestPage(times ...string) string {
+	var builder strings.Builder
+	builder.WriteString(`<html><body><span class="o-AssetTitle__a-HeadlineText">Overnight Oats</span>
+<p><span class="o-Ingredients__a-Ingredient--CheckboxLabel">1 cup oats</span></p>
+<ol><li class="o-Method__m-Step">Soak the oats in a bowl.</li></ol>
+<div class="recipeInfo"><ul class="o-RecipeInfo__m-Time">`)
+	for i := 0; i+1 < len(times); i += 2 {
+		builder.WriteString(`<li><span class="o-RecipeInfo__a-Headline">` + times[i] + `</span><span class="o-RecipeInfo__a-Description">` + times[i+1] + `</span></li>`)
+	}
+	builder.WriteString(`</ul></div></body></html>`)
+	return builder.String()
+}
+
+func TestFoodnetworkParserInactiveTime(t *testing.T) {
+	tests := []struct {
+		times []string
+		want  int
+	}{
+		{[]string{"Inactive:", "1 hr"}, 60},
+		// The overnight rest of the total time is not lost to a shorter inactive time.
+		{[]string{"Total:", "10 min plus overnight", "Inactive:", "1 hr"}, 8 * 60},
+		{[]string{"Inactive:", "1 hr", "Total:", "10 min plus overnight"}, 8 * 60},
+		{[]string{"Total:", "10 min plus overnight", "Inactive:", "10 hr"}, 10 * 60},
+	}
+	for _, test := range tests {
+		node, err := html.Parse(strings.NewReader(foodnetworkTestPage(test.times...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawRecipe, diagnostics, err := NewFoodnetworkParser().ParseDiagnostics(node, DefaultFieldPolicy())
+		if err != nil {
+			t.Errorf("%q: ParseDiagnostics() = %v", test.times, err)
+			continue
+		}
+		if rawRecipe.Metadata.MinutesInactive != test.want {
+			t.Errorf("%q: inactive minutes = %d, want %d", test.times, rawRecipe.Metadata.MinutesInactive, test.want)
+		}
+		if status := diagnostics.Status(FieldInactiveTime); status != FieldFound {
+			t.Errorf("%q: inactive time is %s, want found", test.times, status)
+		}
+		// Food Network does not list equipment, so it is not reported missing.
+		if report, ok := diagnostics.Report(FieldEquipment); ok {
+			t.Errorf("%q: equipment reported as %s", test.times, report)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+const jsonLDScriptSelector = `script[type="application/ld+json"]`
+
+// JSONLDParser parses the schema.org Recipe that many sites embed as JSON-LD in a
+// <script type="application/ld+json"> tag. It works on any site that does so, without
+// site specific selectors. It implements the Parser interface.
+type JSONLDParser struct {
+	scriptSelector css.Selector
+}
+
+// NewJSONLDParser creates a new JSONLDParser.
+func NewJSONLDParser() *JSONLDParser {
+	return &JSONLDParser{
+		scriptSelector: css.MustCompile(jsonLDScriptSelector),
+	}
+}
+
+// ParseRecipe parses the first schema.org Recipe found in the JSON-LD of a HTML node into a
+// RawRecipe struct, or returns an error if there is none or it lacks a required field.
+func (p *JSONLDParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
+	return parseDefault(p, node)
+}
+
+// ParseDiagnostics parses the recipe like ParseRecipe and reports how every field was
+// filled in. It returns an error if there is no recipe or a field policy requires is not found.
+func (p *JSONLDParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	data := p.findRecipe(node)
+	if data == nil {
+		diagnostics := NewDiagnostics()
+		diagnostics.missingAll("no JSON-LD recipe on the page")
+		return nil, diagnostics, ErrParseFailed{Field: "json-ld recipe"}
+	}
+	return parseSchemaRecipe(data, pageBaseURL(node), policy)
+}
+
+// findRecipe returns the first object of type Recipe in the JSON-LD scripts of a page.
+// Scripts that are not valid JSON are skipped.
+func (p *JSONLDParser) findRecipe(node *html.Node) map[string]interface{} {
+	for _, script := range p.scriptSelector.MatchAll(node) {
+		text := ""
+		for child := script.FirstChild; child != nil; child = child.NextSibling {
+			text += child.Data
+		}
+		text = strings.TrimSpace(text)
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "<![CDATA["), "]]>")
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			continue
+		}
+		if data := findSchemaType(value, "Recipe"); data != nil {
+			return unescapeJSONLD(data).(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+// unescapeJSONLD decodes the HTML entities of the strings of a JSON-LD value. Unlike the
+// text of other elements, the text of a script is not decoded by the tokenizer, yet sites
+// escape the strings of their JSON-LD, and often the tags in them, e.g. "&lt;p&gt;".
+func unescapeJSONLD(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, "&") {
+			return html.UnescapeString(v)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = unescapeJSONLD(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = unescapeJSONLD(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"golang.org/x/net/html"
+)
+
+func TestJSONLDParserStepSections(t *testing.T) {
+	page := `<html><head><script type="application/ld+json">
+{"@type": "Recipe", "name": "Apple Pie",
+ "recipeIngredient": ["2 apples", "1 pie crust"],
+ "recipeInstructions": [
+  {"@type": "HowToStep", "text": "Heat the oven."},
+  {"@type": "HowToSection", "name": "Make the filling", "itemListElement": [
+    {"@type": "HowToStep", "text": "Slice the apples."},
+    {"@type": "HowToStep", "text": "Toss with sugar."}]},
+  {"@type": "HowToSection", "name": "Bake", "itemListElement": [
+    {"@type": "HowToStep", "text": "Fill the crust and bake."}]}
+ ]}
+</script></head></html>`
+	node, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawRecipe, err := NewJSONLDParser().ParseRecipe(node)
+	if err != nil {
+		t.Fatalf("ParseRecipe() = %v", err)
+	}
+
+	wantSteps := []string{"Heat the oven.", "Slice the apples.", "Toss with sugar.", "Fill the crust and bake."}
+	if !reflect.DeepEqual(rawRecipe.Steps, wantSteps) {
+		t.Errorf("ParseRecipe() steps = %q, want %q", rawRecipe.Steps, wantSteps)
+	}
+	wantSections := []recipe.StepSection{
+		{Steps: []string{"Heat the oven."}},
+		{Name: "Make the filling", Steps: []string{"Slice the apples.", "Toss with sugar."}},
+		{Name: "Bake", Steps: []string{"Fill the crust and bake."}},
+	}
+	if !reflect.DeepEqual(rawRecipe.StepSections, wantSections) {
+		t.Errorf("ParseRecipe() step sections = %+v, want %+v", rawRecipe.StepSections, wantSections)
+	}
+	if rawRecipe.IngredientGroups != nil {
+		t.Errorf("ParseRecipe() ingredient groups = %+v, want nil", rawRecipe.IngredientGroups)
+	}
+}
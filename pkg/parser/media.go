@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Selectors of the base URL, images and video of a page.
+var (
+	baseSelector      = css.MustCompile("base[href]")
+	canonicalSelector = css.MustCompile(`link[rel="canonical"][href], meta[property="og:url"][content]`)
+	imageTagSelector  = css.MustCompile("img")
+	videoMetaSelector = css.MustCompile(`meta[property="og:video:secure_url"], meta[property="og:video:url"], meta[property="og:video"]`)
+	videoTimeSelector = css.MustCompile(`meta[property="video:duration"]`)
+)
+
+// pageBaseURL returns the URL relative URLs of a page are resolved against: its base
+// element, or else its canonical URL. It returns nil if the page gives neither, in which case
+// the scraper resolves them against the URL the page was fetched from.
+func pageBaseURL(node *html.Node) *url.URL {
+	var canonical *url.URL
+	if link := canonicalSelector.MatchFirst(node); link != nil {
+		href := attr(link, "href")
+		if link.Data == "meta" {
+			href = attr(link, "content")
+		}
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil && u.IsAbs() {
+			canonical = u
+		}
+	}
+
+	// The href of a base element may itself be relative to the page.
+	if base := baseSelector.MatchFirst(node); base != nil {
+		if u, err := url.Parse(strings.TrimSpace(attr(base, "href"))); err == nil {
+			if canonical != nil {
+				u = canonical.ResolveReference(u)
+			}
+			if u.IsAbs() {
+				return u
+			}
+		}
+	}
+	return canonical
+}
+
+// imageFromNode returns the image of an img element. Of the sources in its srcset, the one
+// of the highest resolution is taken, and lazy loaded sources in data attributes are used as
+// well. ok is false if the element has no source.
+func imageFromNode(node *html.Node) (image recipe.Image, ok bool) {
+	width, _ := strconv.Atoi(attr(node, "width"))
+	height, _ := strconv.Atoi(attr(node, "height"))
+	image = recipe.Image{Width: width, Height: height, Alt: strings.TrimSpace(attr(node, "alt"))}
+
+	for _, key := range []string{"srcset", "data-srcset"} {
+		src, srcWidth := bestSrcsetCandidate(attr(node, key))
+		if src == "" {
+			continue
+		}
+		image.URL = src
+		if srcWidth > 0 {
+			// Scale the height of the element to the width of the source.
+			if width > 0 && height > 0 {
+				image.Height = height * srcWidth / width
+			}
+			image.Width = srcWidth
+		}
+		return image, true
+	}
+	for _, key := range []string{"src", "data-src", "data-lazy-src"} {
+		// Lazy loaded images have a data URI as their src until they are shown.
+		if src := strings.TrimSpace(attr(node, key)); src != "" && !strings.HasPrefix(src, "data:") {
+			image.URL = src
+			return image, true
+		}
+	}
+	return image, false
+}
+
+// bestSrcsetCandidate returns the URL of the candidate of a srcset, like
+// "a.jpg 320w, b.jpg 640w" or "a.jpg, b.jpg 2x", with the highest resolution, and its width
+// if it has a width descriptor.
+func bestSrcsetCandidate(srcset string) (src string, width int) {
+	best := 0.0
+	for _, fields := range srcsetCandidates(srcset) {
+		if strings.HasPrefix(fields[0], "data:") {
+			continue
+		}
+		// A candidate without a descriptor is 1x. Widths rank above densities, as they are
+		// not mixed in a valid srcset.
+		resolution, candidateWidth := 1.0, 0
+		if len(fields) > 1 {
+			descriptor := fields[1]
+			value, err := strconv.ParseFloat(descriptor[:len(descriptor)-1], 64)
+			switch {
+			case err != nil:
+				continue
+			case strings.HasSuffix(descriptor, "w"):
+				resolution, candidateWidth = value, int(value)
+			case strings.HasSuffix(descriptor, "x"):
+				resolution = value
+			}
+		}
+		if src == "" || resolution > best {
+			src, width, best = fields[0], candidateWidth, resolution
+		}
+	}
+	return src, width
+}
+
+// srcsetCandidates splits a srcset into the URL and descriptors of each candidate. As in
+// browsers, a comma only separates candidates at the end of a URL or after descriptors, so
+// URLs with commas, like "/w_400,h_300/a.jpg", are kept whole.
+func srcsetCandidates(srcset string) [][]string {
+	candidates := make([][]string, 0)
+	for {
+		srcset = strings.TrimLeft(srcset, ", \t\n\r\f")
+		if srcset == "" {
+			return candidates
+		}
+		end := strings.IndexAny(srcset, " \t\n\r\f")
+		if end < 0 {
+			end = len(srcset)
+		}
+		src := srcset[:end]
+		srcset = srcset[end:]
+
+		fields := []string{strings.TrimRight(src, ",")}
+		if !strings.HasSuffix(src, ",") {
+			end = strings.Index(srcset, ",")
+			if end < 0 {
+				end = len(srcset)
+			}
+			fields = append(fields, strings.Fields(srcset[:end])...)
+			srcset = srcset[end:]
+		}
+		candidates = append(candidates, fields)
+	}
+}
+
+// selectImages returns the images of the img elements selector selects, or that are inside
+// the elements it selects, in order.
+func selectImages(selector css.Selector, node *html.Node) []recipe.Image {
+	images := make([]recipe.Image, 0)
+	for _, match := range selector.MatchAll(node) {
+		imageNodes := []*html.Node{match}
+		if match.Data != "img" {
+			imageNodes = imageTagSelector.MatchAll(match)
+		}
+		for _, imageNode := range imageNodes {
+			if image, ok := imageFromNode(imageNode); ok {
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// selectStepImages returns the images inside the steps selector selects, numbered by the
+// step they are in. Elements without text are not steps, so their images go with the step
+// before them.
+func selectStepImages(selector css.Selector, node *html.Node) []recipe.Image {
+	images := make([]recipe.Image, 0)
+	step := 0
+	for _, stepNode := range selector.MatchAll(node) {
+		if ExtractText(stepNode, TextOptions{}) != "" {
+			step++
+		}
+		for _, imageNode := range imageTagSelector.MatchAll(stepNode) {
+			if image, ok := imageFromNode(imageNode); ok {
+				image.Step = step
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// parseMetaVideo returns the video of the Open Graph meta tags of a page, or nil if it has
+// none.
+func parseMetaVideo(node *html.Node) *recipe.Video {
+	meta := videoMetaSelector.MatchFirst(node)
+	if meta == nil || strings.TrimSpace(attr(meta, "content")) == "" {
+		return nil
+	}
+	video := &recipe.Video{URL: strings.TrimSpace(attr(meta, "content"))}
+	if duration := videoTimeSelector.MatchFirst(node); duration != nil {
+		video.Seconds, _ = strconv.Atoi(strings.TrimSpace(attr(duration, "content")))
+	}
+	return video
+}
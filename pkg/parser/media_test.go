@@ -0,0 +1,32 @@
+package parser
+
+import "testing"
+
+func TestBestSrcsetCandidate(t *testing.T) {
+	tests := []struct {
+		srcset    string
+		wantSrc   string
+		wantWidth int
+	}{
+		{"a.jpg 320w, b.jpg 640w", "b.jpg", 640},
+		{"b.jpg 640w, a.jpg 320w", "b.jpg", 640},
+		{"a.jpg, b.jpg 2x", "b.jpg", 0},
+		{"a.jpg 1.5x,b.jpg 1x", "a.jpg", 0},
+		{"a.jpg", "a.jpg", 0},
+		{"data:image/gif;base64,R0lGOD 1w, a.jpg 320w", "a.jpg", 320},
+		{
+			"https://img.example.com/w_400,h_300,c_fill/a.jpg 400w, https://img.example.com/w_800,h_600,c_fill/a.jpg 800w",
+			"https://img.example.com/w_800,h_600,c_fill/a.jpg", 800,
+		},
+		{"/w_400,h_300/a.jpg, /w_800,h_600/a.jpg 2x", "/w_800,h_600/a.jpg", 0},
+		{"  a.jpg  320w ,\n b.jpg 640w  ", "b.jpg", 640},
+		{"a.jpg bad, b.jpg 320w", "b.jpg", 320},
+		{"", "", 0},
+	}
+	for _, test := range tests {
+		src, width := bestSrcsetCandidate(test.srcset)
+		if src != test.wantSrc || width != test.wantWidth {
+			t.Errorf("bestSrcsetCandidate(%q) = %q, %d, want %q, %d", test.srcset, src, width, test.wantSrc, test.wantWidth)
+		}
+	}
+}
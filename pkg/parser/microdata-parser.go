@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"golang.org/x/net/html"
+)
+
+// MicrodataParser parses a schema.org Recipe marked up with microdata (itemscope, itemtype
+// and itemprop attributes) or RDFa (typeof and property attributes). It works on any site
+// that does so, without site specific selectors. It implements the Parser interface.
+type MicrodataParser struct{}
+
+// NewMicrodataParser creates a new MicrodataParser.
+func NewMicrodataParser() *MicrodataParser {
+	return &MicrodataParser{}
+}
+
+// ParseRecipe parses the first schema.org Recipe item of a HTML node into a RawRecipe
+// struct, or returns an error if there is none or it lacks a required field.
+func (p *MicrodataParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
+	return parseDefault(p, node)
+}
+
+// ParseDiagnostics parses the recipe like ParseRecipe and reports how every field was
+// filled in. It returns an error if there is no recipe or a field policy requires is not found.
+func (p *MicrodataParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	data := p.findRecipe(node)
+	if data == nil {
+		diagnostics := NewDiagnostics()
+		diagnostics.missingAll("no microdata recipe on the page")
+		return nil, diagnostics, ErrParseFailed{Field: "microdata recipe"}
+	}
+	return parseSchemaRecipe(data, pageBaseURL(node), policy)
+}
+
+// findRecipe returns the first item of type Recipe in the tree of node, read into the
+// same form as decoded JSON-LD.
+func (p *MicrodataParser) findRecipe(node *html.Node) map[string]interface{} {
+	if node.Type == html.ElementNode && isMicrodataScope(node) {
+		item := p.readItem(node)
+		if hasSchemaType(item, "Recipe") {
+			return item
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if item := p.findRecipe(child); item != nil {
+			return item
+		}
+	}
+	return nil
+}
+
+// readItem reads the type and properties of the item scoped by node. Every property is
+// a list of values, each either text or a nested item.
+func (p *MicrodataParser) readItem(node *html.Node) map[string]interface{} {
+	types := make([]interface{}, 0)
+	for _, t := range strings.Fields(attr(node, "itemtype") + " " + attr(node, "typeof")) {
+		types = append(types, t)
+	}
+	item := map[string]interface{}{"@type": types}
+	p.readProperties(node, item)
+	return item
+}
+
+// readProperties adds the properties found below node to item, without descending into
+// nested items, whose properties are their own.
+func (p *MicrodataParser) readProperties(node *html.Node, item map[string]interface{}) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		names := microdataPropertyNames(child)
+		scope := isMicrodataScope(child)
+		if len(names) > 0 {
+			var value interface{}
+			if scope {
+				value = p.readItem(child)
+			} else {
+				value = microdataValue(child)
+			}
+			for _, name := range names {
+				values, _ := item[name].([]interface{})
+				item[name] = append(values, value)
+			}
+		}
+
+		if !scope {
+			p.readProperties(child, item)
+		}
+	}
+}
+
+// isMicrodataScope reports whether node starts a new item.
+func isMicrodataScope(node *html.Node) bool {
+	return hasAttr(node, "itemscope") || hasAttr(node, "typeof")
+}
+
+// microdataPropertyNames returns the property names of node. RDFa names may be prefixed
+// (schema:name) or full URLs (http://schema.org/name).
+func microdataPropertyNames(node *html.Node) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Fields(attr(node, "itemprop") + " " + attr(node, "property")) {
+		if i := strings.LastIndexAny(name, "/:#"); i >= 0 {
+			name = name[i+1:]
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// microdataValue returns the value of a property element: its content attribute, the URL
+// of links and media, the machine readable value of time and data elements, or its text.
+func microdataValue(node *html.Node) string {
+	if hasAttr(node, "content") {
+		return attr(node, "content")
+	}
+	switch node.Data {
+	case "a", "area", "link":
+		return attr(node, "href")
+	case "img", "audio", "video", "source", "embed", "iframe":
+		return attr(node, "src")
+	case "object":
+		return attr(node, "data")
+	case "time":
+		if hasAttr(node, "datetime") {
+			return attr(node, "datetime")
+		}
+	case "data", "meter":
+		return attr(node, "value")
+	}
+	return ExtractText(node, TextOptions{KeepLines: true})
+}
@@ -0,0 +1,488 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+)
+
+// htmlTagRE matches the markup some sites leave in the text values of schema.org properties.
+var htmlTagRE = regexp.MustCompile(`<[^>]*>`)
+
+// parseSchemaRecipe parses a schema.org Recipe, decoded from JSON-LD or read from microdata,
+// into a RawRecipe struct and reports how every field was filled in. Properties are text,
+// objects, or lists of either. Relative URLs are resolved against base. It returns an error
+// if a field policy requires is not found.
+func parseSchemaRecipe(data map[string]interface{}, base *url.URL, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	diagnostics := NewDiagnostics()
+
+	name := schemaText(data["name"])
+	diagnostics.foundIf(name != "", FieldName, "no name property")
+
+	description := schemaText(data["description"])
+	diagnostics.foundIf(description != "", FieldDescription, "no description property")
+
+	ingredientValue, ok := data["recipeIngredient"]
+	if !ok {
+		// Older markup uses the deprecated ingredients property.
+		ingredientValue = data["ingredients"]
+	}
+	ingredients := schemaTexts(ingredientValue)
+	diagnostics.foundIf(len(ingredients) > 0, FieldIngredients, "no recipeIngredient property")
+
+	sections := schemaStepSections(data["recipeInstructions"])
+	diagnostics.foundIf(len(sections) > 0, FieldSteps, "no recipeInstructions property")
+
+	equipment := recipe.NormalizeEquipment(append(schemaTexts(data["tool"]), schemaStepTools(data["recipeInstructions"])...))
+	diagnostics.foundIf(len(equipment) > 0, FieldEquipment, "no tool property")
+
+	metadata := parseSchemaMetadata(data, diagnostics)
+	metadata.ResolveURLs(base)
+	if err := diagnostics.Check(policy); err != nil {
+		return nil, diagnostics, err
+	}
+
+	rawRecipe := &recipe.RawRecipe{
+		Name:                   name,
+		Description:            description,
+		IngredientDescriptions: ingredients,
+		Equipment:              equipment,
+		Metadata:               metadata,
+	}
+	rawRecipe.SetStepSections(sections)
+	return rawRecipe, diagnostics, nil
+}
+
+// parseSchemaMetadata parses the times, yield, tags, image, nutrition, author, rating and
+// dates of a recipe.
+func parseSchemaMetadata(data map[string]interface{}, diagnostics *Diagnostics) recipe.RecipeMetadata {
+	metadata := recipe.RecipeMetadata{EstimatedCalories: -1}
+	diagnostics.Missing(FieldDifficulty, "not part of schema.org")
+
+	// schema.org has no inactive time, but free text times may mention one.
+	diagnostics.Missing(FieldInactiveTime, "no inactive time in the times")
+	metadata.MinutesToPrep = parseSchemaDuration(data, "prepTime", FieldPrepTime, &metadata, diagnostics)
+	metadata.MinutesToCook = parseSchemaDuration(data, "cookTime", FieldCookTime, &metadata, diagnostics)
+	metadata.MinutesTotal = parseSchemaDuration(data, "totalTime", FieldTotalTime, &metadata, diagnostics)
+	if metadata.MinutesTotal == 0 && metadata.MinutesToPrep+metadata.MinutesToCook > 0 {
+		metadata.MinutesTotal = metadata.MinutesToPrep + metadata.MinutesToCook + metadata.MinutesInactive
+		diagnostics.Defaulted(FieldTotalTime, "sum of the prep and cook times")
+	}
+
+	metadata.Servings = parseSchemaYield(data["recipeYield"])
+	switch {
+	case metadata.Servings.Max > 0:
+		diagnostics.Found(FieldServings)
+	case metadata.Servings.Alternative != "":
+		diagnostics.Defaulted(FieldServings, fmt.Sprintf("no servings in %q, keeping the text", metadata.Servings.Alternative))
+	default:
+		diagnostics.Missing(FieldServings, "no recipeYield property")
+	}
+
+	// Keywords are usually one comma separated string, categories and cuisines are
+	// useful tags as well.
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, key := range []string{"recipeCategory", "recipeCuisine", "keywords"} {
+		for _, text := range schemaTexts(data[key]) {
+			for _, tag := range strings.Split(text, ",") {
+				tag = strings.TrimSpace(tag)
+				if tag != "" && !seen[strings.ToLower(tag)] {
+					seen[strings.ToLower(tag)] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	metadata.Tags = tags
+	diagnostics.foundIf(len(tags) > 0, FieldTags, "no recipeCategory, recipeCuisine or keywords property")
+
+	metadata.AddImages(schemaImages(data["image"])...)
+	metadata.AddImages(schemaStepImages(data["recipeInstructions"])...)
+	diagnostics.foundIf(metadata.ImageURL != "", FieldImage, "no image property")
+	metadata.Video = schemaVideo(data["video"])
+	diagnostics.foundIf(metadata.Video != nil, FieldVideo, "no video property")
+
+	facts := make([]nutritionFact, 0)
+	if nutrition := schemaObject(data["nutrition"]); nutrition != nil {
+		for key, value := range nutrition {
+			if text := schemaText(value); text != "" && !strings.HasPrefix(key, "@") {
+				facts = append(facts, nutritionFact{label: key, text: text})
+			}
+		}
+		// Map order is random, keep the warnings in a stable order.
+		sort.Slice(facts, func(i, j int) bool { return facts[i].label < facts[j].label })
+	}
+	parseNutrition(facts, "no nutrition property", &metadata, diagnostics)
+
+	metadata.Author = schemaName(data["author"])
+	diagnostics.foundIf(metadata.Author != "", FieldAuthor, "no author property")
+	diagnostics.Missing(FieldRating, "no aggregateRating property")
+	if rating := schemaObject(data["aggregateRating"]); rating != nil {
+		text := schemaText(rating["ratingValue"])
+		if best := schemaText(rating["bestRating"]); best != "" {
+			text += "/" + best
+		}
+		if value, ok := parseRating(text); ok {
+			metadata.Rating = value
+			diagnostics.Found(FieldRating)
+		} else if text != "" {
+			diagnostics.Defaulted(FieldRating, fmt.Sprintf("no rating in %q", text))
+		}
+		for _, key := range []string{"reviewCount", "ratingCount"} {
+			if count, ok := parseCount(schemaText(rating[key])); ok {
+				metadata.ReviewCount = count
+				break
+			}
+		}
+	}
+	for _, date := range []struct {
+		key    string
+		target *time.Time
+		field  string
+	}{
+		{"datePublished", &metadata.PublishedAt, FieldPublished},
+		{"dateModified", &metadata.UpdatedAt, FieldUpdated},
+	} {
+		text := schemaText(data[date.key])
+		t, ok := parseDate(text)
+		switch {
+		case ok:
+			*date.target = t
+			diagnostics.Found(date.field)
+		case text != "":
+			diagnostics.Defaulted(date.field, fmt.Sprintf("no date in %q", text))
+		default:
+			diagnostics.Missing(date.field, "no "+date.key+" property")
+		}
+	}
+
+	return metadata
+}
+
+// parseSchemaDuration returns the minutes of the duration of property key, and reports it as
+// field. Durations should be ISO 8601, but free text ones like "1 hour" are used as well.
+func parseSchemaDuration(data map[string]interface{}, key, field string, metadata *recipe.RecipeMetadata, diagnostics *Diagnostics) int {
+	text := schemaText(data[key])
+	if text == "" {
+		diagnostics.Missing(field, "no "+key+" property")
+		return 0
+	}
+	return parseTimeText(text, field, metadata, diagnostics)
+}
+
+// findSchemaType searches a schema.org value, including arrays and @graph lists, for the first
+// object of the given schema.org type.
+func findSchemaType(value interface{}, typeName string) map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if data := findSchemaType(item, typeName); data != nil {
+				return data
+			}
+		}
+	case map[string]interface{}:
+		if hasSchemaType(v, typeName) {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findSchemaType(graph, typeName)
+		}
+	}
+	return nil
+}
+
+// hasSchemaType reports whether an object has the given schema.org type. The type may
+// be a list of types, and may be a full URL like http://schema.org/Recipe.
+func hasSchemaType(data map[string]interface{}, typeName string) bool {
+	for _, t := range schemaTexts(data["@type"]) {
+		if t == typeName || strings.HasSuffix(t, "/"+typeName) || strings.HasSuffix(t, ":"+typeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaStepSections splits recipeInstructions into sections at its HowToSection objects.
+// Steps outside of a HowToSection are in sections without a name. Empty sections are
+// left out.
+func schemaStepSections(value interface{}) []recipe.StepSection {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	// An ItemList wraps the steps and sections in its elements.
+	if len(items) == 1 {
+		if list, ok := items[0].(map[string]interface{}); ok && !hasSchemaType(list, "HowToSection") {
+			if elements, ok := list["itemListElement"]; ok {
+				return schemaStepSections(elements)
+			}
+		}
+	}
+
+	sections := make([]recipe.StepSection, 0)
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok && hasSchemaType(object, "HowToSection") {
+			sections = append(sections, recipe.StepSection{
+				Name:  schemaText(object["name"]),
+				Steps: schemaSteps(object["itemListElement"]),
+			})
+			continue
+		}
+		if len(sections) == 0 || sections[len(sections)-1].Name != "" {
+			sections = append(sections, recipe.StepSection{Steps: make([]string, 0)})
+		}
+		last := &sections[len(sections)-1]
+		last.Steps = append(last.Steps, schemaSteps(item)...)
+	}
+
+	nonEmpty := make([]recipe.StepSection, 0, len(sections))
+	for _, section := range sections {
+		if len(section.Steps) > 0 {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return nonEmpty
+}
+
+// schemaSteps flattens recipeInstructions, which is either text, a list of text, or a
+// list of HowToStep and HowToSection objects, into a list of steps.
+func schemaSteps(value interface{}) []string {
+	steps := make([]string, 0)
+	switch v := value.(type) {
+	case string:
+		for _, line := range strings.Split(v, "\n") {
+			if step := cleanSchemaText(line); step != "" {
+				steps = append(steps, step)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			steps = append(steps, schemaSteps(item)...)
+		}
+	case map[string]interface{}:
+		// HowToSection and ItemList nest their steps, HowToStep may nest HowToDirections.
+		if items, ok := v["itemListElement"]; ok {
+			return schemaSteps(items)
+		}
+		step := schemaText(v["text"])
+		if step == "" {
+			step = schemaText(v["name"])
+		}
+		if step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// schemaText returns a schema.org value as clean text. Lists return their first text, objects
+// their @value or name.
+func schemaText(value interface{}) string {
+	texts := schemaTexts(value)
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[0]
+}
+
+// schemaTexts returns a schema.org value that is text or a list of text as a list of clean,
+// non-empty texts.
+func schemaTexts(value interface{}) []string {
+	texts := make([]string, 0)
+	switch v := value.(type) {
+	case string:
+		if text := cleanSchemaText(v); text != "" {
+			texts = append(texts, text)
+		}
+	case float64:
+		texts = append(texts, strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		for _, item := range v {
+			texts = append(texts, schemaTexts(item)...)
+		}
+	case map[string]interface{}:
+		if text, ok := v["@value"]; ok {
+			return schemaTexts(text)
+		}
+		return schemaTexts(v["name"])
+	}
+	return texts
+}
+
+// cleanSchemaText strips HTML tags that sites leave in their structured data, and collapses
+// whitespace.
+func cleanSchemaText(text string) string {
+	return NormalizeText(htmlTagRE.ReplaceAllString(text, " "))
+}
+
+// schemaObject returns a value that is an object, or the first object of a list.
+func schemaObject(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if data := schemaObject(item); data != nil {
+				return data
+			}
+		}
+	}
+	return nil
+}
+
+// schemaImages returns the images of a recipe or step. An image is either a URL, an
+// ImageObject, or a list of either.
+func schemaImages(value interface{}) []recipe.Image {
+	images := make([]recipe.Image, 0)
+	switch v := value.(type) {
+	case string:
+		if url := strings.TrimSpace(v); url != "" {
+			images = append(images, recipe.Image{URL: url})
+		}
+	case []interface{}:
+		for _, item := range v {
+			images = append(images, schemaImages(item)...)
+		}
+	case map[string]interface{}:
+		url := schemaText(v["url"])
+		if url == "" {
+			url = schemaText(v["contentUrl"])
+		}
+		alt := schemaText(v["caption"])
+		if alt == "" {
+			alt = schemaText(v["description"])
+		}
+		if url != "" {
+			images = append(images, recipe.Image{
+				URL:    url,
+				Width:  schemaPixels(v["width"]),
+				Height: schemaPixels(v["height"]),
+				Alt:    alt,
+			})
+		}
+	}
+	return images
+}
+
+// schemaStepImages returns the images of the steps of recipeInstructions, numbered by the
+// step they are of in the order of schemaSteps.
+func schemaStepImages(value interface{}) []recipe.Image {
+	images := make([]recipe.Image, 0)
+	step := 0
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			step += len(schemaSteps(v))
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			if items, ok := v["itemListElement"]; ok {
+				walk(items)
+				return
+			}
+			if len(schemaSteps(v)) == 0 {
+				return
+			}
+			step++
+			for _, image := range schemaImages(v["image"]) {
+				image.Step = step
+				images = append(images, image)
+			}
+		}
+	}
+	walk(value)
+	return images
+}
+
+// schemaStepTools returns the tools of the HowToStep objects of recipeInstructions. A tool is
+// text, a HowToTool object, or a list of either.
+func schemaStepTools(value interface{}) []string {
+	tools := make([]string, 0)
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			tools = append(tools, schemaStepTools(item)...)
+		}
+	case map[string]interface{}:
+		if items, ok := v["itemListElement"]; ok {
+			return schemaStepTools(items)
+		}
+		tools = append(tools, schemaTexts(v["tool"])...)
+	}
+	return tools
+}
+
+// schemaVideo returns the video of a VideoObject, or nil if there is none.
+func schemaVideo(value interface{}) *recipe.Video {
+	data := schemaObject(value)
+	if data == nil {
+		return nil
+	}
+	video := &recipe.Video{URL: schemaText(data["contentUrl"])}
+	if video.URL == "" {
+		video.URL = schemaText(data["embedUrl"])
+	}
+	if thumbnails := schemaImages(data["thumbnailUrl"]); len(thumbnails) > 0 {
+		video.ThumbnailURL = thumbnails[0].URL
+	} else if thumbnails := schemaImages(data["thumbnail"]); len(thumbnails) > 0 {
+		video.ThumbnailURL = thumbnails[0].URL
+	}
+	video.Seconds, _ = recipe.ParseISODurationSeconds(schemaText(data["duration"]))
+	if video.URL == "" && video.ThumbnailURL == "" {
+		return nil
+	}
+	return video
+}
+
+// schemaPixels returns a width or height in pixels, e.g. 800, "800px" or a
+// QuantitativeValue, or 0 if it is not one.
+func schemaPixels(value interface{}) int {
+	if data, ok := value.(map[string]interface{}); ok {
+		value = data["value"]
+	}
+	pixels, _ := parseCount(schemaText(value))
+	return pixels
+}
+
+// schemaName returns the name of a schema.org Person or Organization, e.g. an author, which
+// may be given as text, an object or a list of either. The names of a list are joined.
+func schemaName(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			if name := schemaName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	case map[string]interface{}:
+		return schemaText(v["name"])
+	}
+	return schemaText(value)
+}
+
+// parseSchemaYield parses a recipeYield, e.g. 4, "4 servings", "4-6", "1 loaf" or a list of
+// these, into a ServingRange.
+func parseSchemaYield(value interface{}) recipe.ServingRange {
+	texts := schemaTexts(value)
+	for _, text := range texts {
+		if servings, err := recipe.ParseYield(text); err == nil {
+			return servings
+		}
+	}
+	if len(texts) > 0 {
+		return recipe.ServingRange{Alternative: texts[0]}
+	}
+	return recipe.ServingRange{}
+}
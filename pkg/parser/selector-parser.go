@@ -0,0 +1,517 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// SelectorParserConfig defines how to parse the recipes of a site with CSS selectors. It is
+// read from the parser section of a YAML site definition.
+type SelectorParserConfig struct {
+	Name        FieldConfig `yaml:"name"`
+	Description FieldConfig `yaml:"description"`
+	Ingredients FieldConfig `yaml:"ingredients"`
+	Steps       FieldConfig `yaml:"steps"`
+	Tags        FieldConfig `yaml:"tags"`
+	// Equipment selects the equipment the site lists for a recipe. Recipes without it have
+	// their equipment extracted from the steps.
+	Equipment FieldConfig `yaml:"equipment"`
+	// Image selects the recipe images. Its attribute defaults to src, in which case the
+	// source of the highest resolution in the srcset of an image is taken. Images inside
+	// the steps are kept as images of the steps.
+	Image FieldConfig `yaml:"image"`
+	// ImageAltAttribute is the attribute of the image with its alt text.
+	ImageAltAttribute string `yaml:"image_alt_attribute"`
+
+	// Author, Rating, ReviewCount, Published and Updated select the attribution, the
+	// rating of the reviews and the dates of a recipe. Ratings like "4.5 out of 5" are
+	// scaled to 5. Dates not selected are read from the meta tags of the page.
+	Author      FieldConfig `yaml:"author"`
+	Rating      FieldConfig `yaml:"rating"`
+	ReviewCount FieldConfig `yaml:"review_count"`
+	Published   FieldConfig `yaml:"published"`
+	Updated     FieldConfig `yaml:"updated"`
+	// Video selects the video element of a recipe, its attribute defaults to src and its
+	// poster is the thumbnail. The video of the meta tags of the page is used otherwise.
+	Video FieldConfig `yaml:"video"`
+
+	// IngredientHeadings and StepHeadings select the headings of ingredient groups and step
+	// sections, e.g. "For the crust", among the ingredients and steps.
+	IngredientHeadings string `yaml:"ingredient_headings"`
+	StepHeadings       string `yaml:"step_headings"`
+
+	Metadata  MetadataConfig  `yaml:"metadata"`
+	Nutrition NutritionConfig `yaml:"nutrition"`
+	// Difficulty maps the text of the difficulty to a difficulty, e.g. "Easy: 1".
+	Difficulty map[string]recipe.RecipeDifficulty `yaml:"difficulty"`
+
+	// URLPrefix is put in front of image URLs without a scheme on pages without a base or
+	// canonical URL to resolve them against, e.g. "https:" for protocol relative URLs or
+	// "https://example.com" for absolute paths.
+	URLPrefix string `yaml:"url_prefix"`
+	// PlaceholderImages are regular expressions of image URLs that are placeholders rather
+	// than pictures of the recipe, and are left out.
+	PlaceholderImages []string `yaml:"placeholder_images"`
+}
+
+// FieldConfig selects a field of a recipe. The field is the text of the selected elements,
+// or the value of Attribute if set. A required field that is not found fails the parse.
+type FieldConfig struct {
+	Selector  string `yaml:"selector"`
+	Attribute string `yaml:"attribute"`
+	Required  bool   `yaml:"required"`
+}
+
+// MetadataConfig selects metadata that sites list as headline and value pairs, like
+// "Prep: 10 min". Headlines maps the text of a headline to the field it holds, one of
+// difficulty, total_time, prep_time, cook_time or servings.
+type MetadataConfig struct {
+	Selector         string            `yaml:"selector"`
+	HeadlineSelector string            `yaml:"headline_selector"`
+	ValueSelector    string            `yaml:"value_selector"`
+	Headlines        map[string]string `yaml:"headlines"`
+}
+
+// NutritionConfig selects the nutrition facts of a recipe. The labels and amounts in each
+// nutrition panel are paired in order, e.g. the dt and dd elements of a dl. Labels like
+// "Total Fat", "Dietary Fiber" or "Serving Size" are recognised by their text.
+type NutritionConfig struct {
+	Selector       string `yaml:"selector"`
+	LabelSelector  string `yaml:"label_selector"`
+	AmountSelector string `yaml:"amount_selector"`
+}
+
+// The metadata fields a headline can map to.
+var metadataFields = []string{FieldDifficulty, FieldTotalTime, FieldPrepTime, FieldCookTime, FieldInactiveTime, FieldServings}
+
+// SelectorParser parses recipes with the CSS selectors of a site definition, so that a site
+// is added or fixed by editing YAML instead of Go. It implements the Parser interface.
+type SelectorParser struct {
+	cfg SelectorParserConfig
+
+	name        *fieldSelector
+	description *fieldSelector
+	ingredients *fieldSelector
+	steps       *fieldSelector
+	tags        *fieldSelector
+	equipment   *fieldSelector
+	image       *fieldSelector
+	author      *fieldSelector
+	rating      *fieldSelector
+	reviewCount *fieldSelector
+	published   *fieldSelector
+	updated     *fieldSelector
+	video       *fieldSelector
+
+	ingredientHeadings css.Selector
+	stepHeadings       css.Selector
+
+	metadataSelector css.Selector
+	headlineSelector css.Selector
+	valueSelector    css.Selector
+
+	nutritionSelector       css.Selector
+	nutritionLabelSelector  css.Selector
+	nutritionAmountSelector css.Selector
+
+	placeholderImages []*regexp.Regexp
+}
+
+// fieldSelector is a compiled FieldConfig.
+type fieldSelector struct {
+	name      string
+	selector  css.Selector
+	attribute string
+	required  bool
+}
+
+// NewSelectorParser compiles the selectors and patterns of cfg into a SelectorParser, or
+// returns an error if any is invalid.
+func NewSelectorParser(cfg SelectorParserConfig) (*SelectorParser, error) {
+	p := &SelectorParser{cfg: cfg}
+
+	if cfg.Image.Attribute == "" {
+		cfg.Image.Attribute = "src"
+	}
+	if cfg.Video.Attribute == "" {
+		cfg.Video.Attribute = "src"
+	}
+	fields := []struct {
+		name   string
+		cfg    FieldConfig
+		target **fieldSelector
+	}{
+		{FieldName, cfg.Name, &p.name},
+		{FieldDescription, cfg.Description, &p.description},
+		{FieldIngredients, cfg.Ingredients, &p.ingredients},
+		{FieldSteps, cfg.Steps, &p.steps},
+		{FieldTags, cfg.Tags, &p.tags},
+		{FieldEquipment, cfg.Equipment, &p.equipment},
+		{FieldImage, cfg.Image, &p.image},
+		{FieldAuthor, cfg.Author, &p.author},
+		{FieldRating, cfg.Rating, &p.rating},
+		{"review_count", cfg.ReviewCount, &p.reviewCount},
+		{FieldPublished, cfg.Published, &p.published},
+		{FieldUpdated, cfg.Updated, &p.updated},
+		{FieldVideo, cfg.Video, &p.video},
+	}
+	for _, field := range fields {
+		if field.cfg.Selector == "" {
+			if field.cfg.Required {
+				return nil, fmt.Errorf("required field %s has no selector", field.name)
+			}
+			continue
+		}
+		selector, err := css.Compile(field.cfg.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of field %s: %w", field.name, err)
+		}
+		*field.target = &fieldSelector{
+			name:      field.name,
+			selector:  selector,
+			attribute: field.cfg.Attribute,
+			required:  field.cfg.Required,
+		}
+	}
+	if p.name == nil {
+		return nil, fmt.Errorf("field name has no selector")
+	}
+
+	for _, heading := range []struct {
+		name     string
+		selector string
+		target   *css.Selector
+	}{
+		{"ingredient", cfg.IngredientHeadings, &p.ingredientHeadings},
+		{"step", cfg.StepHeadings, &p.stepHeadings},
+	} {
+		if heading.selector == "" {
+			continue
+		}
+		selector, err := css.Compile(heading.selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s heading selector: %w", heading.name, err)
+		}
+		*heading.target = selector
+	}
+
+	if cfg.Metadata.Selector != "" {
+		var err error
+		if p.metadataSelector, err = css.Compile(cfg.Metadata.Selector); err != nil {
+			return nil, fmt.Errorf("invalid metadata selector: %w", err)
+		}
+		if p.headlineSelector, err = css.Compile(cfg.Metadata.HeadlineSelector); err != nil {
+			return nil, fmt.Errorf("invalid metadata headline selector: %w", err)
+		}
+		if p.valueSelector, err = css.Compile(cfg.Metadata.ValueSelector); err != nil {
+			return nil, fmt.Errorf("invalid metadata value selector: %w", err)
+		}
+	}
+	if cfg.Nutrition.Selector != "" {
+		var err error
+		if p.nutritionSelector, err = css.Compile(cfg.Nutrition.Selector); err != nil {
+			return nil, fmt.Errorf("invalid nutrition selector: %w", err)
+		}
+		if p.nutritionLabelSelector, err = css.Compile(cfg.Nutrition.LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid nutrition label selector: %w", err)
+		}
+		if p.nutritionAmountSelector, err = css.Compile(cfg.Nutrition.AmountSelector); err != nil {
+			return nil, fmt.Errorf("invalid nutrition amount selector: %w", err)
+		}
+	}
+	for headline, field := range cfg.Metadata.Headlines {
+		known := false
+		for _, name := range metadataFields {
+			known = known || name == field
+		}
+		if !known {
+			return nil, fmt.Errorf("headline %q maps to unknown metadata field %q", headline, field)
+		}
+	}
+
+	for _, pattern := range cfg.PlaceholderImages {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid placeholder image pattern: %w", err)
+		}
+		p.placeholderImages = append(p.placeholderImages, re)
+	}
+	return p, nil
+}
+
+// ParseRecipe parses a HTML node into a RawRecipe struct, or returns an error if a required
+// field is missing.
+func (p *SelectorParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
+	return parseDefault(p, node)
+}
+
+// ParseDiagnostics parses a HTML node into a RawRecipe struct and reports how every field
+// was filled in. It returns an error if a field that policy or the site definition
+// requires is not found.
+func (p *SelectorParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	diagnostics := NewDiagnostics()
+	rawRecipe := &recipe.RawRecipe{
+		Name:        p.name.first(node, diagnostics),
+		Description: p.description.first(node, diagnostics),
+		Equipment:   recipe.NormalizeEquipment(p.equipment.all(node, diagnostics)),
+		Metadata:    p.parseRecipeMetadata(node, diagnostics),
+	}
+	rawRecipe.SetIngredientGroups(ingredientGroups(p.ingredients.groups(node, p.ingredientHeadings, isGroupHeading, diagnostics)))
+	rawRecipe.SetStepSections(stepSections(p.steps.groups(node, p.stepHeadings, nil, diagnostics)))
+
+	required := make([]string, 0)
+	fields := []struct {
+		name     string
+		selector *fieldSelector
+	}{
+		{FieldName, p.name}, {FieldDescription, p.description}, {FieldIngredients, p.ingredients},
+		{FieldSteps, p.steps}, {FieldEquipment, p.equipment}, {FieldTags, p.tags}, {FieldImage, p.image},
+		{FieldAuthor, p.author}, {FieldRating, p.rating}, {FieldPublished, p.published}, {FieldUpdated, p.updated},
+		{FieldVideo, p.video},
+	}
+	for _, field := range fields {
+		switch {
+		case field.selector == nil:
+			// Dates and videos not selected may have been read from the meta tags.
+			if _, ok := diagnostics.Report(field.name); !ok {
+				diagnostics.Missing(field.name, "not selected by the site")
+			}
+		case field.selector.required:
+			required = append(required, field.name)
+		}
+	}
+	if err := diagnostics.Check(policy.with(required...)); err != nil {
+		return nil, diagnostics, err
+	}
+	return rawRecipe, diagnostics, nil
+}
+
+// parseRecipeMetadata parses the headline and value pairs, nutrition, tags, attribution,
+// rating, dates and image of a recipe.
+func (p *SelectorParser) parseRecipeMetadata(node *html.Node, diagnostics *Diagnostics) recipe.RecipeMetadata {
+	metadata := recipe.RecipeMetadata{EstimatedCalories: -1}
+	if p.nutritionSelector != nil {
+		facts := selectNutritionFacts(p.nutritionSelector, p.nutritionLabelSelector, p.nutritionAmountSelector, node)
+		parseNutrition(facts, "no element matches "+p.cfg.Nutrition.Selector, &metadata, diagnostics)
+	} else {
+		parseNutrition(nil, "not selected by the site", &metadata, diagnostics)
+	}
+
+	if p.metadataSelector != nil {
+		for _, item := range p.metadataSelector.MatchAll(node) {
+			headlineNode := p.headlineSelector.MatchFirst(item)
+			valueNode := p.valueSelector.MatchFirst(item)
+			if headlineNode == nil || valueNode == nil {
+				continue
+			}
+			headline := ExtractText(headlineNode, TextOptions{})
+			text := ExtractText(valueNode, TextOptions{})
+
+			field := p.cfg.Metadata.Headlines[headline]
+			found := true
+			switch field {
+			case FieldDifficulty:
+				metadata.Difficulty, found = p.cfg.Difficulty[text]
+			// Times are reported by parseTimeText.
+			case FieldTotalTime:
+				metadata.MinutesTotal = parseTimeText(text, field, &metadata, diagnostics)
+				continue
+			case FieldPrepTime:
+				metadata.MinutesToPrep = parseTimeText(text, field, &metadata, diagnostics)
+				continue
+			case FieldCookTime:
+				metadata.MinutesToCook = parseTimeText(text, field, &metadata, diagnostics)
+				continue
+			case FieldInactiveTime:
+				metadata.MinutesInactive = parseTimeText(text, field, &metadata, diagnostics)
+				continue
+			case FieldServings:
+				metadata.Servings = parseServingsText(text)
+				found = metadata.Servings.Max > 0
+			default:
+				continue
+			}
+			if found {
+				diagnostics.Found(field)
+			} else {
+				diagnostics.Defaulted(field, fmt.Sprintf("could not parse %q", text))
+			}
+		}
+	}
+	for _, field := range metadataFields {
+		if _, ok := diagnostics.Report(field); !ok {
+			diagnostics.Missing(field, "no headline")
+		}
+	}
+
+	metadata.Tags = p.tags.all(node, diagnostics)
+
+	metadata.Author = p.author.first(node, diagnostics)
+	if text := p.rating.first(node, diagnostics); text != "" {
+		if rating, ok := parseRating(text); ok {
+			metadata.Rating = rating
+		} else {
+			diagnostics.Defaulted(FieldRating, fmt.Sprintf("no rating in %q", text))
+		}
+	}
+	// The review count is not a field of its own, it goes with the rating.
+	metadata.ReviewCount, _ = parseCount(p.reviewCount.first(node, NewDiagnostics()))
+	for _, date := range []struct {
+		selector *fieldSelector
+		target   *time.Time
+		field    string
+	}{
+		{p.published, &metadata.PublishedAt, FieldPublished},
+		{p.updated, &metadata.UpdatedAt, FieldUpdated},
+	} {
+		if text := date.selector.first(node, diagnostics); text != "" {
+			if t, ok := parseDate(text); ok {
+				*date.target = t
+			} else {
+				diagnostics.Defaulted(date.field, fmt.Sprintf("no date in %q", text))
+			}
+		}
+	}
+	parseMetaDates(node, &metadata, diagnostics)
+
+	metadata.Video = p.parseVideo(node)
+	diagnostics.foundIf(metadata.Video != nil, FieldVideo, "no video")
+
+	if p.image == nil {
+		return metadata
+	}
+	base := pageBaseURL(node)
+	placeholder := false
+	for _, imageNode := range p.image.selector.MatchAll(node) {
+		image, _ := imageFromNode(imageNode)
+		if p.image.attribute != "src" {
+			image.URL = attr(imageNode, p.image.attribute)
+		}
+		if p.cfg.ImageAltAttribute != "" {
+			image.Alt = attr(imageNode, p.cfg.ImageAltAttribute)
+		}
+		if image.URL == "" {
+			continue
+		}
+		image.URL = p.absoluteURL(recipe.ResolveURL(base, image.URL))
+		if p.isPlaceholder(image.URL) {
+			placeholder = true
+			continue
+		}
+		metadata.AddImages(image)
+	}
+	if p.steps != nil {
+		for _, image := range selectStepImages(p.steps.selector, node) {
+			image.URL = p.absoluteURL(recipe.ResolveURL(base, image.URL))
+			metadata.AddImages(image)
+		}
+	}
+	switch {
+	case metadata.ImageURL != "":
+		diagnostics.Found(FieldImage)
+	case placeholder:
+		diagnostics.Missing(FieldImage, "placeholder image")
+	default:
+		diagnostics.Missing(FieldImage, "no element matches "+p.cfg.Image.Selector)
+	}
+
+	return metadata
+}
+
+// isPlaceholder reports whether an image URL matches a placeholder image pattern.
+func (p *SelectorParser) isPlaceholder(src string) bool {
+	for _, re := range p.placeholderImages {
+		if re.MatchString(src) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVideo returns the video the site selects, with its poster as the thumbnail, or else
+// the video of the meta tags of the page. It returns nil if there is no video.
+func (p *SelectorParser) parseVideo(node *html.Node) *recipe.Video {
+	if p.video != nil {
+		if videoNode := p.video.selector.MatchFirst(node); videoNode != nil {
+			base := pageBaseURL(node)
+			video := &recipe.Video{
+				URL:          p.absoluteURL(recipe.ResolveURL(base, attr(videoNode, p.video.attribute))),
+				ThumbnailURL: p.absoluteURL(recipe.ResolveURL(base, attr(videoNode, "poster"))),
+			}
+			if video.URL != "" || video.ThumbnailURL != "" {
+				return video
+			}
+		}
+	}
+	video := parseMetaVideo(node)
+	if video != nil {
+		video.URL = p.absoluteURL(recipe.ResolveURL(pageBaseURL(node), video.URL))
+	}
+	return video
+}
+
+// absoluteURL puts the URL prefix in front of a URL without a scheme, which the page did not
+// give a base URL for.
+func (p *SelectorParser) absoluteURL(link string) string {
+	if u, err := url.Parse(link); link == "" || err == nil && u.Scheme != "" {
+		return link
+	}
+	return p.cfg.URLPrefix + link
+}
+
+// first returns the value of the first element selected, or "" if the field is not
+// configured or missing.
+func (f *fieldSelector) first(node *html.Node, diagnostics *Diagnostics) string {
+	values := f.all(node, diagnostics)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// groups returns the non-empty texts of every element selected, grouped under the
+// headings before them. Values of an attribute are not grouped.
+func (f *fieldSelector) groups(node *html.Node, heading css.Selector, isHeading func(string) bool, diagnostics *Diagnostics) []TextGroup {
+	if f == nil || f.attribute != "" {
+		values := f.all(node, diagnostics)
+		if len(values) == 0 {
+			return nil
+		}
+		return []TextGroup{{Texts: values}}
+	}
+
+	groups, warnings := SelectGroups(f.selector, heading, isHeading, node, f.name, TextOptions{})
+	diagnostics.Warn(warnings...)
+	diagnostics.foundIf(len(groups) > 0, f.name, "no element with a value")
+	return groups
+}
+
+// all returns the non-empty values of every element selected, and reports whether any
+// were found.
+func (f *fieldSelector) all(node *html.Node, diagnostics *Diagnostics) []string {
+	values := make([]string, 0)
+	if f == nil {
+		return values
+	}
+
+	if f.attribute == "" {
+		var warnings []ParseWarning
+		values, warnings = SelectTexts(f.selector, node, f.name, TextOptions{})
+		diagnostics.Warn(warnings...)
+	} else {
+		for _, match := range f.selector.MatchAll(node) {
+			if value := strings.TrimSpace(attr(match, f.attribute)); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	diagnostics.foundIf(len(values) > 0, f.name, "no element with a value")
+	return values
+}
@@ -0,0 +1,260 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Elements whose text is apart from the text around them, e.g. the steps of an
+// instructions list.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "br": true, "tr": true, "td": true, "th": true,
+	"section": true, "article": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// Elements that never hold text a reader sees.
+var hiddenElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+}
+
+// Characters that sites put in text but that only get in the way.
+var textReplacer = strings.NewReplacer(
+	"\u00a0", " ", // non-breaking space
+	"\u200b", "", // zero width space
+	"\u00ad", "", // soft hyphen
+	"\ufeff", "", // byte order mark
+)
+
+// TextOptions configures how text is extracted from HTML.
+type TextOptions struct {
+	// KeepLines puts the text of block elements on lines of their own instead of
+	// joining everything into one line.
+	KeepLines bool
+	// KeepLinks writes links as [text](href) instead of just their text.
+	KeepLinks bool
+	// Skip leaves out the elements it returns true for, and their descendants.
+	Skip func(*html.Node) bool
+}
+
+// ParseWarning is a problem with a field that did not stop the recipe from being parsed,
+// e.g. an element that was selected but has no text.
+type ParseWarning struct {
+	Field   string `json:"field" yaml:"field"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (w ParseWarning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// ExtractText returns the text of node and all its descendants, including text inside
+// inline markup like links and emphasis. Comments, scripts and styles are left out,
+// entities that were escaped twice are decoded, and whitespace is normalised. A nil
+// node has no text.
+func ExtractText(node *html.Node, opts TextOptions) string {
+	if node == nil {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	writeText(&builder, node, opts, true)
+
+	if !opts.KeepLines {
+		return NormalizeText(builder.String())
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(builder.String(), "\n") {
+		if line = NormalizeText(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeText writes the text of node to builder. Block elements are surrounded by line
+// breaks, which NormalizeText turns into spaces unless lines are kept.
+func writeText(builder *strings.Builder, node *html.Node, opts TextOptions, root bool) {
+	switch node.Type {
+	case html.TextNode:
+		builder.WriteString(node.Data)
+		return
+	case html.ElementNode:
+		if hiddenElements[node.Data] || (!root && opts.Skip != nil && opts.Skip(node)) {
+			return
+		}
+		if opts.KeepLinks && node.Data == "a" && attr(node, "href") != "" {
+			linkOpts := opts
+			linkOpts.KeepLinks = false
+			if text := ExtractText(node, linkOpts); text != "" {
+				fmt.Fprintf(builder, "[%s](%s)", text, attr(node, "href"))
+			}
+			return
+		}
+	case html.DocumentNode:
+	default:
+		// Comments and doctypes have no text.
+		return
+	}
+
+	block := node.Type == html.ElementNode && blockElements[node.Data]
+	if block {
+		builder.WriteString("\n")
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeText(builder, child, opts, false)
+	}
+	if block {
+		builder.WriteString("\n")
+	}
+}
+
+// NormalizeText drops invisible characters and collapses runs of whitespace into single
+// spaces. Text of the tokenizer has its entities decoded already, so it does not decode them.
+func NormalizeText(text string) string {
+	text = textReplacer.Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// SelectText returns the first non-empty text of the elements selector matches in node.
+// Matches without text are reported as warnings for field. If nothing matches, or no
+// match has text, the text is empty.
+func SelectText(selector css.Selector, node *html.Node, field string, opts TextOptions) (string, []ParseWarning) {
+	texts, warnings := selectTexts(selector, node, field, opts, true)
+	if len(texts) == 0 {
+		return "", warnings
+	}
+	return texts[0], warnings
+}
+
+// SelectTexts returns the non-empty texts of every element selector matches in node.
+// Matches without text are left out and reported as warnings for field.
+func SelectTexts(selector css.Selector, node *html.Node, field string, opts TextOptions) ([]string, []ParseWarning) {
+	return selectTexts(selector, node, field, opts, false)
+}
+
+func selectTexts(selector css.Selector, node *html.Node, field string, opts TextOptions, first bool) ([]string, []ParseWarning) {
+	texts := make([]string, 0)
+	var warnings []ParseWarning
+	if selector == nil || node == nil {
+		return texts, warnings
+	}
+
+	for i, match := range selector.MatchAll(node) {
+		text := ExtractText(match, opts)
+		if text == "" {
+			warnings = append(warnings, ParseWarning{
+				Field:   field,
+				Message: fmt.Sprintf("match %d (<%s>) has no text", i+1, match.Data),
+			})
+			continue
+		}
+		texts = append(texts, text)
+		if first {
+			break
+		}
+	}
+	return texts, warnings
+}
+
+// TextGroup is the texts below a heading, e.g. the ingredients "For the crust". Texts
+// before the first heading are in a group without a name.
+type TextGroup struct {
+	Name  string
+	Texts []string
+}
+
+// SelectGroups returns the texts of the elements item matches in node, grouped under
+// the closest heading before them that heading matches, in document order. Items whose
+// text isHeading returns true for, if it is set, start a group as well. Items without
+// text are left out and reported as warnings for field, and so are empty groups.
+func SelectGroups(item, heading css.Selector, isHeading func(text string) bool, node *html.Node, field string, opts TextOptions) ([]TextGroup, []ParseWarning) {
+	groups := []TextGroup{{Texts: make([]string, 0)}}
+	var warnings []ParseWarning
+	if item == nil || node == nil {
+		return nil, warnings
+	}
+
+	matches := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch {
+			case heading != nil && heading.Match(child):
+				groups = append(groups, TextGroup{Name: groupName(ExtractText(child, opts)), Texts: make([]string, 0)})
+			case item.Match(child):
+				matches++
+				text := ExtractText(child, opts)
+				switch {
+				case text == "":
+					warnings = append(warnings, ParseWarning{
+						Field:   field,
+						Message: fmt.Sprintf("match %d (<%s>) has no text", matches, child.Data),
+					})
+				case isHeading != nil && isHeading(text):
+					groups = append(groups, TextGroup{Name: groupName(text), Texts: make([]string, 0)})
+				default:
+					groups[len(groups)-1].Texts = append(groups[len(groups)-1].Texts, text)
+				}
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+
+	for _, group := range groups[1:] {
+		if len(group.Texts) == 0 {
+			warnings = append(warnings, ParseWarning{Field: field, Message: fmt.Sprintf("group %q is empty", group.Name)})
+		}
+	}
+	return nonEmptyGroups(groups), warnings
+}
+
+// nonEmptyGroups returns the groups that have texts.
+func nonEmptyGroups(groups []TextGroup) []TextGroup {
+	nonEmpty := make([]TextGroup, 0, len(groups))
+	for _, group := range groups {
+		if len(group.Texts) > 0 {
+			nonEmpty = append(nonEmpty, group)
+		}
+	}
+	return nonEmpty
+}
+
+// isGroupHeading reports whether the text of an ingredient is the heading of a group
+// rather than an ingredient, e.g. "For the crust:". Steps may well end with a colon.
+func isGroupHeading(text string) bool {
+	return strings.HasSuffix(text, ":") && len(text) <= 60
+}
+
+// groupName returns the name of a group from the text of its heading.
+func groupName(text string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), ":"))
+}
+
+// attr returns the value of the attribute key of node, or "" if it has none.
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether node has the attribute key, even if it is empty.
+func hasAttr(node *html.Node, key string) bool {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"  Mix the\n\tflour  ", "Mix the flour"},
+		{"Salt\u00a0and pepper\u200b", "Salt and pepper"},
+		// The tokenizer decoded the entities of the page already, what is left is text.
+		{"Use &lt; 1 cup", "Use &lt; 1 cup"},
+		{"Fish &amp; Chips", "Fish &amp; Chips"},
+	}
+	for _, test := range tests {
+		if got := NormalizeText(test.text); got != test.want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParsersDecodeEntitiesOnce(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser Parser
+		page   string
+		want   string
+	}{
+		{
+			"json-ld",
+			NewJSONLDParser(),
+			`<script type="application/ld+json">{"@type": "Recipe", "name": "Mac &amp; Cheese &amp;lt;3",
+"recipeIngredient": ["1 cup macaroni"], "recipeInstructions": ["&lt;p&gt;Boil.&lt;/p&gt;"]}</script>`,
+			"Mac & Cheese &lt;3",
+		},
+		{
+			"microdata",
+			NewMicrodataParser(),
+			`<div itemscope itemtype="https://schema.org/Recipe"><h1 itemprop="name">Mac &amp;amp; Cheese &amp;lt;3</h1>
+<span itemprop="recipeIngredient">1 cup macaroni</span><p itemprop="recipeInstructions">Boil.</p></div>`,
+			"Mac &amp; Cheese &lt;3",
+		},
+	}
+	for _, test := range tests {
+		node, err := html.Parse(strings.NewReader("<html><body>" + test.page + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawRecipe, err := test.parser.ParseRecipe(node)
+		if err != nil {
+			t.Errorf("%s: ParseRecipe() = %v", test.name, err)
+			continue
+		}
+		if rawRecipe.Name != test.want {
+			t.Errorf("%s: name = %q, want %q", test.name, rawRecipe.Name, test.want)
+		}
+		if len(rawRecipe.Steps) != 1 || rawRecipe.Steps[0] != "Boil." {
+			t.Errorf("%s: steps = %q, want [Boil.]", test.name, rawRecipe.Steps)
+		}
+	}
+}
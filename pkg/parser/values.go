@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	css "github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// parseServingsText parses a yield like "4", "4–6 people" or "2 loaves" into a ServingRange
+// struct. Yields that cannot be parsed are kept as the alternative text.
+func parseServingsText(text string) recipe.ServingRange {
+	servings, err := recipe.ParseYield(text)
+	if err != nil {
+		return recipe.ServingRange{Alternative: text}
+	}
+	return servings
+}
+
+// parseTimeText parses a free text or ISO 8601 time for field, reports it, and returns it in
+// minutes, or 0 if it cannot be parsed. For ranges like "20–30 minutes" the longest time is
+// used. Inactive time in the text, like "overnight", is not part of the minutes of prep and
+// cook times but is kept as the inactive time of metadata.
+func parseTimeText(text, field string, metadata *recipe.RecipeMetadata, diagnostics *Diagnostics) int {
+	duration, err := recipe.ParseDuration(text)
+	if err != nil {
+		diagnostics.Defaulted(field, fmt.Sprintf("could not parse time %q, using 0", text))
+		return 0
+	}
+	diagnostics.Found(field)
+
+	if field != FieldInactiveTime {
+		addInactiveTime(metadata, duration.InactiveMinutes, diagnostics)
+	}
+	if field == FieldPrepTime || field == FieldCookTime {
+		return duration.Minutes
+	}
+	return duration.Total()
+}
+
+// addInactiveTime keeps the inactive minutes of a time if they are longer than the inactive
+// time of metadata so far, as several times of a recipe may mention the same rest.
+func addInactiveTime(metadata *recipe.RecipeMetadata, minutes int, diagnostics *Diagnostics) {
+	if minutes <= metadata.MinutesInactive {
+		return
+	}
+	metadata.MinutesInactive = minutes
+	diagnostics.Found(FieldInactiveTime)
+}
+
+// REs for the ratings, counts and dates that sites show.
+var (
+	ratingRE      = regexp.MustCompile(`(\d+(?:\.\d+)?)(?:\s*(?:/|out of)\s*(\d+(?:\.\d+)?))?`)
+	countRE       = regexp.MustCompile(`\d[\d,]*`)
+	isoDateRE     = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?)?`)
+	writtenDateRE = regexp.MustCompile(`(?i)(?:[a-z]+\.? \d{1,2},? \d{4})|(?:\d{1,2} [a-z]+\.?,? \d{4})`)
+)
+
+// The layouts of the dates matched by isoDateRE and writtenDateRE.
+var (
+	isoDateLayouts = []string{
+		time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05",
+		"2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02",
+	}
+	writtenDateLayouts = []string{
+		"January 2, 2006", "January 2 2006", "Jan 2, 2006", "Jan 2 2006", "Jan. 2, 2006",
+		"2 January 2006", "2 Jan 2006", "2 January, 2006",
+	}
+)
+
+// parseRating parses a rating like "4.5", "4.5 out of 5" or "9/10" into a rating out of 5.
+func parseRating(text string) (float64, bool) {
+	matches := ratingRE.FindStringSubmatch(text)
+	if matches == nil {
+		return 0, false
+	}
+	rating, _ := strconv.ParseFloat(matches[1], 64)
+	if best, _ := strconv.ParseFloat(matches[2], 64); best > 0 && best != 5 {
+		rating = rating / best * 5
+	}
+	return rating, rating > 0 && rating <= 5
+}
+
+// parseCount parses a count like "1,234 reviews".
+func parseCount(text string) (int, bool) {
+	count, err := strconv.Atoi(strings.ReplaceAll(countRE.FindString(text), ",", ""))
+	return count, err == nil
+}
+
+// parseDate parses a date like "2023-03-12T10:00:00-04:00", "March 12, 2023" or
+// "Updated 12 March 2023". Dates without a zone are taken to be UTC.
+func parseDate(text string) (time.Time, bool) {
+	for _, match := range []struct {
+		re      *regexp.Regexp
+		layouts []string
+	}{{isoDateRE, isoDateLayouts}, {writtenDateRE, writtenDateLayouts}} {
+		date := match.re.FindString(text)
+		if date == "" {
+			continue
+		}
+		for _, layout := range match.layouts {
+			if t, err := time.Parse(layout, date); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// The meta tags pages give their publish and update dates in.
+var (
+	publishedMetaSelector = css.MustCompile(`meta[property="article:published_time"], meta[itemprop="datePublished"], meta[name="date"]`)
+	updatedMetaSelector   = css.MustCompile(`meta[property="article:modified_time"], meta[property="og:updated_time"], meta[itemprop="dateModified"]`)
+)
+
+// parseMetaDates parses the publish and update dates of the meta tags of a page into
+// metadata, unless they are already set, and reports the dates that were not reported yet.
+func parseMetaDates(node *html.Node, metadata *recipe.RecipeMetadata, diagnostics *Diagnostics) {
+	for _, date := range []struct {
+		selector css.Selector
+		target   *time.Time
+		field    string
+	}{
+		{publishedMetaSelector, &metadata.PublishedAt, FieldPublished},
+		{updatedMetaSelector, &metadata.UpdatedAt, FieldUpdated},
+	} {
+		if date.target.IsZero() {
+			for _, meta := range date.selector.MatchAll(node) {
+				if t, ok := parseDate(attr(meta, "content")); ok {
+					*date.target = t
+					break
+				}
+			}
+		}
+		if !date.target.IsZero() {
+			diagnostics.Found(date.field)
+		} else if _, ok := diagnostics.Report(date.field); !ok {
+			diagnostics.Missing(date.field, "no date on the page")
+		}
+	}
+}
+
+// nutritionFact is a nutrition fact as a page labels it, e.g. "Total Fat" and "7 g".
+type nutritionFact struct {
+	label string
+	text  string
+}
+
+// parseNutrition parses the nutrition facts of a recipe into metadata, and reports the
+// calories and nutrition. A fact labelled serving size gives the serving size, and facts of
+// anything else than a nutrient are left out. Amounts that cannot be parsed are reported as
+// warnings. reason is why there are no facts, if there are none.
+func parseNutrition(facts []nutritionFact, reason string, metadata *recipe.RecipeMetadata, diagnostics *Diagnostics) {
+	if len(facts) == 0 {
+		diagnostics.Missing(FieldCalories, reason)
+		diagnostics.Missing(FieldNutrition, reason)
+		return
+	}
+
+	nutrition := recipe.NewNutrition()
+	for _, fact := range facts {
+		if label := strings.ToLower(fact.label); strings.Contains(label, "serving") && strings.Contains(label, "size") {
+			nutrition.ServingSize = fact.text
+			continue
+		}
+		nutrient, ok := recipe.ParseNutrient(fact.label)
+		if !ok {
+			continue
+		}
+		if err := nutrition.Set(nutrient, fact.text); err != nil {
+			diagnostics.Warn(ParseWarning{Field: FieldNutrition, Message: err.Error()})
+		}
+	}
+
+	if nutrition.Calories >= 0 {
+		metadata.EstimatedCalories = int(math.Round(nutrition.Calories))
+		diagnostics.Found(FieldCalories)
+	} else {
+		diagnostics.Missing(FieldCalories, "no calories in the nutrition facts")
+	}
+	if nutrition.Empty() {
+		diagnostics.Defaulted(FieldNutrition, "no nutrition fact could be parsed")
+		return
+	}
+	metadata.Nutrition = nutrition
+	diagnostics.Found(FieldNutrition)
+}
+
+// selectNutritionFacts returns the nutrition facts of the panels panel selects, pairing the
+// labels and amounts in each panel in order, e.g. the dt and dd elements of a dl.
+func selectNutritionFacts(panel, label, amount css.Selector, node *html.Node) []nutritionFact {
+	facts := make([]nutritionFact, 0)
+	for _, panelNode := range panel.MatchAll(node) {
+		labels, amounts := label.MatchAll(panelNode), amount.MatchAll(panelNode)
+		for i := 0; i < len(labels) && i < len(amounts); i++ {
+			facts = append(facts, nutritionFact{
+				label: ExtractText(labels[i], TextOptions{}),
+				text:  ExtractText(amounts[i], TextOptions{}),
+			})
+		}
+	}
+	return facts
+}
+
+// ingredientGroups turns the texts of grouped ingredients into ingredient groups.
+func ingredientGroups(groups []TextGroup) []recipe.IngredientGroup {
+	ingredientGroups := make([]recipe.IngredientGroup, 0, len(groups))
+	for _, group := range groups {
+		ingredientGroups = append(ingredientGroups, recipe.IngredientGroup{Name: group.Name, Ingredients: group.Texts})
+	}
+	return ingredientGroups
+}
+
+// stepSections turns the texts of grouped steps into step sections.
+func stepSections(groups []TextGroup) []recipe.StepSection {
+	sections := make([]recipe.StepSection, 0, len(groups))
+	for _, group := range groups {
+		sections = append(sections, recipe.StepSection{Name: group.Name, Steps: group.Texts})
+	}
+	return sections
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
@@ -8,54 +11,403 @@ import (
 	"golang.org/x/net/html"
 )
 
+// The CSS selectors used to parse the Wikibooks Cookbook
 const (
-	wikibooksNameSelector        = "h1#firstHeading"
-	wikibooksIngredientsSelector = "ul li:has(a[title^='Cookbook:'])"
-	wikibooksStepsSelector       = "ol.li.a[title]"
+	wikibooksNameSelector     = "h1#firstHeading"
+	wikibooksContentSelector  = "div.mw-parser-output"
+	wikibooksSummarySelector  = "div.mw-parser-output table tr"
+	wikibooksCategorySelector = "div#mw-normal-catlinks li a"
+	wikibooksImageSelector    = "div.mw-parser-output img"
+	// The footer says when the page was last edited, e.g. "This page was last edited on 12
+	// March 2023, at 10:00."
+	wikibooksLastEditedSelector = "#footer-info-lastmod"
+	wikibooksListItemSelector   = "li"
 )
 
+// Section headings of the ingredients, equipment and steps of a recipe.
+var (
+	wikibooksIngredientHeadings = map[string]bool{"ingredients": true}
+	wikibooksEquipmentHeadings  = map[string]bool{"equipment": true, "tools": true, "utensils": true}
+	wikibooksStepHeadings       = map[string]bool{
+		"procedure": true, "directions": true, "method": true, "instructions": true,
+		"preparation": true, "steps": true,
+	}
+)
+
+// Some helper REs.
+var (
+	wikibooksDifficultyRE = regexp.MustCompile(`([1-5])o5dots|(?i)difficulty\D*([1-5])`)
+)
+
+// WikibooksParser is a parser for the recipes of the Wikibooks Cookbook. It implements the
+// Parser interface. It takes a HTML node and parses it into a RawRecipe struct.
 type WikibooksParser struct {
 	nameSelector       css.Selector
-	ingredientSelector css.Selector
-	stepsSelector      css.Selector
+	contentSelector    css.Selector
+	summarySelector    css.Selector
+	categorySelector   css.Selector
+	imageSelector      css.Selector
+	listItemSelector   css.Selector
+	lastEditedSelector css.Selector
 }
 
 func NewWikibooksParser() *WikibooksParser {
 	// Compile the required css selectors, panic if any fail
-	nameSelector := css.MustCompile(wikibooksNameSelector)
-	ingredientSelector := css.MustCompile(wikibooksIngredientsSelector)
-	stepsSelector := css.MustCompile(wikibooksStepsSelector)
-
 	return &WikibooksParser{
-		nameSelector:       nameSelector,
-		ingredientSelector: ingredientSelector,
-		stepsSelector:      stepsSelector,
+		nameSelector:       css.MustCompile(wikibooksNameSelector),
+		contentSelector:    css.MustCompile(wikibooksContentSelector),
+		summarySelector:    css.MustCompile(wikibooksSummarySelector),
+		categorySelector:   css.MustCompile(wikibooksCategorySelector),
+		imageSelector:      css.MustCompile(wikibooksImageSelector),
+		listItemSelector:   css.MustCompile(wikibooksListItemSelector),
+		lastEditedSelector: css.MustCompile(wikibooksLastEditedSelector),
 	}
 }
 
+// ParseRecipe parses a HTML node into a RawRecipe struct, or returns an error if it fails.
 func (p *WikibooksParser) ParseRecipe(node *html.Node) (*recipe.RawRecipe, error) {
-	name, err := p.parseName(node)
-	if err != nil {
-		return nil, err
+	return parseDefault(p, node)
+}
+
+// ParseDiagnostics parses a HTML node into a RawRecipe struct and reports how every field
+// was filled in. It returns an error if a field policy requires is not found.
+func (p *WikibooksParser) ParseDiagnostics(node *html.Node, policy FieldPolicy) (*recipe.RawRecipe, *Diagnostics, error) {
+	diagnostics := NewDiagnostics()
+	rawRecipe := &recipe.RawRecipe{
+		Name:                   p.parseName(node, diagnostics),
+		IngredientDescriptions: make([]string, 0),
+		Steps:                  make([]string, 0),
 	}
 
-	// ings := p.ingredientSelector.MatchAll(node)
-	// for _, ing := range ings {
-	// 	html.Render(os.Stdout, ing)
-	// }
+	content := p.contentSelector.MatchFirst(node)
+	if content == nil {
+		for _, field := range []string{FieldDescription, FieldIngredients, FieldSteps, FieldEquipment} {
+			diagnostics.Missing(field, "no page content")
+		}
+	} else {
+		sections := p.parseSections(content)
 
-	return &recipe.RawRecipe{
-		Name: name,
-	}, nil
+		rawRecipe.Description = p.parseDescription(sections)
+		diagnostics.foundIf(rawRecipe.Description != "", FieldDescription, "no paragraph before the first heading")
+
+		rawRecipe.SetIngredientGroups(ingredientGroups(p.parseListItems(sections, wikibooksIngredientHeadings, isGroupHeading)))
+		diagnostics.foundIf(len(rawRecipe.IngredientDescriptions) > 0, FieldIngredients, "no list below an ingredients heading")
+
+		equipment := make([]string, 0)
+		for _, group := range p.parseListItems(sections, wikibooksEquipmentHeadings, nil) {
+			equipment = append(equipment, group.Texts...)
+		}
+		rawRecipe.Equipment = recipe.NormalizeEquipment(equipment)
+		diagnostics.foundIf(len(rawRecipe.Equipment) > 0, FieldEquipment, "no list below an equipment heading")
+
+		steps := p.parseListItems(sections, wikibooksStepHeadings, nil)
+		if len(steps) == 0 {
+			// Short recipes describe the procedure in paragraphs instead of a list.
+			steps = p.parseParagraphs(sections, wikibooksStepHeadings)
+		}
+		rawRecipe.SetStepSections(stepSections(steps))
+		diagnostics.foundIf(len(rawRecipe.Steps) > 0, FieldSteps, "no list or paragraph below a procedure heading")
+	}
+	rawRecipe.Metadata = p.parseRecipeMetadata(node, diagnostics)
+
+	if err := diagnostics.Check(policy); err != nil {
+		return nil, diagnostics, err
+	}
+	return rawRecipe, diagnostics, nil
 }
 
-func (p *WikibooksParser) parseName(node *html.Node) (string, error) {
+// parseName returns the title of the page without its Cookbook: prefix.
+func (p *WikibooksParser) parseName(node *html.Node, diagnostics *Diagnostics) string {
 	titleNode := p.nameSelector.MatchAll(node)
 	if len(titleNode) != 1 {
-		return "", ErrParseFailed{Field: "name"}
+		diagnostics.Missing(FieldName, fmt.Sprintf("%d page titles", len(titleNode)))
+		return ""
+	}
+
+	title := ExtractText(titleNode[0], TextOptions{})
+	title = strings.TrimSpace(strings.TrimPrefix(title, "Cookbook:"))
+	diagnostics.foundIf(title != "", FieldName, "empty page title")
+	return title
+}
+
+// wikibooksSection is the content below a heading of the page. The content before the
+// first heading is a section without a heading.
+type wikibooksSection struct {
+	heading string
+	nodes   []*html.Node
+}
+
+// parseSections splits the top level content of a page at its level 2 headings. Lower
+// level headings, e.g. the parts of a recipe, stay in their section. Section elements,
+// which some markup wraps the content of each heading in, are looked into.
+func (p *WikibooksParser) parseSections(content *html.Node) []wikibooksSection {
+	sections := []wikibooksSection{{}}
+	var walk func(parent *html.Node)
+	walk = func(parent *html.Node) {
+		for child := parent.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.Data == "section" {
+				walk(child)
+				continue
+			}
+			if heading := wikibooksHeading(child); heading != "" {
+				sections = append(sections, wikibooksSection{heading: heading})
+				continue
+			}
+			cur := &sections[len(sections)-1]
+			cur.nodes = append(cur.nodes, child)
+		}
+	}
+	walk(content)
+	return sections
+}
+
+// wikibooksHeading returns the lower case text of a level 2 heading, which is either a
+// h2 element or, in newer markup, a div.mw-heading2 wrapping one.
+func wikibooksHeading(node *html.Node) string {
+	if node.Data == "div" && strings.Contains(attr(node, "class"), "mw-heading2") {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.Data == "h2" {
+				node = child
+				break
+			}
+		}
+	}
+	if node.Data != "h2" {
+		return ""
 	}
 
-	title := titleNode[0].FirstChild.Data
+	// Older markup puts the edit link inside the heading.
+	text := ExtractText(node, TextOptions{Skip: func(n *html.Node) bool {
+		return strings.Contains(attr(n, "class"), "mw-editsection")
+	}})
+	return strings.ToLower(text)
+}
+
+// parseListItems returns the items of the lists in the sections with one of headings,
+// grouped under the lower level headings in the sections. Items of nested lists are
+// items of their own. Items isHeading returns true for, if it is set, start a group.
+func (p *WikibooksParser) parseListItems(sections []wikibooksSection, headings map[string]bool, isHeading func(string) bool) []TextGroup {
+	return p.parseGroups(sections, headings, isHeading, func(node *html.Node) []string {
+		items := make([]string, 0)
+		for _, item := range p.listItemSelector.MatchAll(node) {
+			if text := ExtractText(item, TextOptions{Skip: isList}); text != "" {
+				items = append(items, text)
+			}
+		}
+		return items
+	})
+}
+
+// parseParagraphs returns the paragraphs in the sections with one of headings, grouped
+// under the lower level headings in the sections.
+func (p *WikibooksParser) parseParagraphs(sections []wikibooksSection, headings map[string]bool) []TextGroup {
+	return p.parseGroups(sections, headings, nil, func(node *html.Node) []string {
+		if node.Data != "p" {
+			return nil
+		}
+		if text := ExtractText(node, TextOptions{}); text != "" {
+			return []string{text}
+		}
+		return nil
+	})
+}
+
+// parseGroups returns the texts items finds in the content of the sections with one of
+// headings. A lower level heading, or a text isHeading returns true for, starts a group.
+func (p *WikibooksParser) parseGroups(sections []wikibooksSection, headings map[string]bool, isHeading func(string) bool, items func(*html.Node) []string) []TextGroup {
+	groups := []TextGroup{{Texts: make([]string, 0)}}
+	for _, section := range sections {
+		if !headings[section.heading] {
+			continue
+		}
+		for _, node := range section.nodes {
+			if name := wikibooksSubheading(node); name != "" {
+				groups = append(groups, TextGroup{Name: name, Texts: make([]string, 0)})
+				continue
+			}
+			for _, text := range items(node) {
+				if isHeading != nil && isHeading(text) {
+					groups = append(groups, TextGroup{Name: groupName(text), Texts: make([]string, 0)})
+					continue
+				}
+				groups[len(groups)-1].Texts = append(groups[len(groups)-1].Texts, text)
+			}
+		}
+	}
+	return nonEmptyGroups(groups)
+}
+
+// wikibooksSubheading returns the text of a heading below level 2, e.g. "For the crust".
+// Besides h3 to h6 elements, wiki markup makes headings of definition terms (";Crust")
+// and of paragraphs that are only bold text.
+func wikibooksSubheading(node *html.Node) string {
+	if node.Data == "div" && strings.Contains(attr(node, "class"), "mw-heading") {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode {
+				node = child
+				break
+			}
+		}
+	}
+
+	var heading *html.Node
+	switch node.Data {
+	case "h3", "h4", "h5", "h6":
+		heading = node
+	case "dl", "p":
+		// The only element must be a definition term or bold text, without anything else.
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.TextNode && strings.TrimSpace(child.Data) == "":
+			case child.Type == html.ElementNode && heading == nil &&
+				(child.Data == "dt" && node.Data == "dl" || (child.Data == "b" || child.Data == "strong") && node.Data == "p"):
+				heading = child
+			default:
+				return ""
+			}
+		}
+	}
+	if heading == nil {
+		return ""
+	}
+
+	text := ExtractText(heading, TextOptions{Skip: func(n *html.Node) bool {
+		return strings.Contains(attr(n, "class"), "mw-editsection")
+	}})
+	return groupName(text)
+}
+
+// parseDescription returns the first paragraph before the first heading.
+func (p *WikibooksParser) parseDescription(sections []wikibooksSection) string {
+	for _, node := range sections[0].nodes {
+		if node.Data != "p" {
+			continue
+		}
+		if text := ExtractText(node, TextOptions{}); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// parseRecipeMetadata parses the recipe summary table, the categories and the image of
+// a recipe.
+func (p *WikibooksParser) parseRecipeMetadata(node *html.Node, diagnostics *Diagnostics) recipe.RecipeMetadata {
+	metadata := recipe.RecipeMetadata{EstimatedCalories: -1}
+	diagnostics.Missing(FieldCalories, "not shown on the page")
+	diagnostics.Missing(FieldNutrition, "not shown on the page")
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	addTag := func(tag string) {
+		tag = NormalizeText(tag)
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	// The recipe summary is a table of headlines and values.
+	for _, row := range p.summarySelector.MatchAll(node) {
+		var headline, value *html.Node
+		for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+			switch {
+			case cell.Type != html.ElementNode:
+			case cell.Data == "th" && headline == nil:
+				headline = cell
+			case cell.Data == "td" && value == nil:
+				value = cell
+			}
+		}
+		if headline == nil || value == nil {
+			continue
+		}
+		text := ExtractText(value, TextOptions{})
+
+		switch strings.TrimSuffix(strings.ToLower(ExtractText(headline, TextOptions{})), ":") {
+		case "category":
+			addTag(text)
+		case "servings", "yield":
+			metadata.Servings = parseServingsText(text)
+			if metadata.Servings.Max > 0 {
+				diagnostics.Found(FieldServings)
+			} else {
+				diagnostics.Defaulted(FieldServings, fmt.Sprintf("no servings in %q, keeping the text", text))
+			}
+		case "time":
+			metadata.MinutesTotal = parseTimeText(text, FieldTotalTime, &metadata, diagnostics)
+		case "difficulty":
+			metadata.Difficulty = p.parseDifficulty(value)
+			if metadata.Difficulty > 0 {
+				diagnostics.Found(FieldDifficulty)
+			} else {
+				diagnostics.Defaulted(FieldDifficulty, "no difficulty dots")
+			}
+		}
+	}
+	for _, field := range []string{FieldDifficulty, FieldTotalTime, FieldInactiveTime, FieldServings} {
+		if _, ok := diagnostics.Report(field); !ok {
+			diagnostics.Missing(field, "not in the recipe summary")
+		}
+	}
+	// The summary only has the total time.
+	diagnostics.Missing(FieldPrepTime, "not shown on the page")
+	diagnostics.Missing(FieldCookTime, "not shown on the page")
+
+	for _, categoryNode := range p.categorySelector.MatchAll(node) {
+		addTag(ExtractText(categoryNode, TextOptions{}))
+	}
+	metadata.Tags = tags
+	diagnostics.foundIf(len(tags) > 0, FieldTags, "no category")
+
+	// The images that are not icons, e.g. the difficulty dots.
+	for _, imageNode := range p.imageSelector.MatchAll(node) {
+		width, err := strconv.Atoi(attr(imageNode, "width"))
+		if (err == nil && width < 100) || wikibooksDifficultyRE.MatchString(attr(imageNode, "src")) {
+			continue
+		}
+		if image, ok := imageFromNode(imageNode); ok {
+			metadata.AddImages(image)
+		}
+	}
+	diagnostics.foundIf(metadata.ImageURL != "", FieldImage, "no image of at least 100 pixels")
+	diagnostics.Missing(FieldVideo, "not shown on the page")
+
+	// Pages are written by many editors and not rated, and only the last edit is shown.
+	diagnostics.Missing(FieldAuthor, "wiki pages have no single author")
+	diagnostics.Missing(FieldRating, "not shown on the page")
+	diagnostics.Missing(FieldPublished, "not shown on the page")
+	if lastEdited := p.lastEditedSelector.MatchFirst(node); lastEdited != nil {
+		metadata.UpdatedAt, _ = parseDate(ExtractText(lastEdited, TextOptions{}))
+	}
+	diagnostics.foundIf(!metadata.UpdatedAt.IsZero(), FieldUpdated, "no last edit date")
+
+	metadata.ResolveURLs(pageBaseURL(node))
+	return metadata
+}
+
+// parseDifficulty returns the difficulty shown as an image of one to five dots.
+func (p *WikibooksParser) parseDifficulty(value *html.Node) recipe.RecipeDifficulty {
+	if digit := ExtractText(value, TextOptions{}); len(digit) == 1 && digit[0] >= '1' && digit[0] <= '5' {
+		return recipe.RecipeDifficulty(digit[0] - '0')
+	}
+
+	text := ""
+	for _, imageNode := range p.imageSelector.MatchAll(value) {
+		text += " " + attr(imageNode, "alt") + " " + attr(imageNode, "src")
+	}
+
+	matches := wikibooksDifficultyRE.FindStringSubmatch(text)
+	if matches == nil {
+		return 0
+	}
+	digit := matches[1] + matches[2]
+	return recipe.RecipeDifficulty(digit[0] - '0')
+}
 
-	return strings.TrimPrefix(title, "Cookbook:"), nil
+// isList reports whether node is a list, to leave nested lists out of the text of an item.
+func isList(node *html.Node) bool {
+	return node.Type == html.ElementNode && (node.Data == "ul" || node.Data == "ol")
 }
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testWikibooksPage = `<html><body>
+<h1 id="firstHeading">Cookbook:Pancakes</h1>
+<div class="mw-parser-output">
+<table><tbody>
+  <tr><th>Category</th><td><a href="/wiki/Category:Breakfast">Breakfast recipes</a></td></tr>
+  <tr><th>Servings</th><td>4</td></tr>
+  <tr><th>Time</th><td>30 minutes</td></tr>
+</tbody></table>
+<p>Pancakes are flat cakes fried in a pan.</p>
+<h2><span class="mw-headline">Ingredients</span><span class="mw-editsection">[edit]</span></h2>
+<ul><li>1 cup flour</li><li>1 egg</li><li>1 cup milk</li></ul>
+<h2><span class="mw-headline">Procedure</span></h2>
+<ol><li>Whisk everything together.</li><li>Fry in a hot pan.</li></ol>
+<h2>Notes</h2>
+<ul><li>Serve with syrup.</li></ul>
+</div>
+<div id="mw-normal-catlinks"><ul><li><a href="/wiki/Category:Recipes">Recipes</a></li></ul></div>
+</body></html>`
+
+func TestWikibooksParser(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(testWikibooksPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawRecipe, err := NewWikibooksParser().ParseRecipe(node)
+	if err != nil {
+		t.Fatalf("ParseRecipe() = %v", err)
+	}
+
+	if rawRecipe.Name != "Pancakes" || rawRecipe.Description != "Pancakes are flat cakes fried in a pan." {
+		t.Errorf("ParseRecipe() name, description = %q, %q", rawRecipe.Name, rawRecipe.Description)
+	}
+	if want := []string{"1 cup flour", "1 egg", "1 cup milk"}; !reflect.DeepEqual(rawRecipe.IngredientDescriptions, want) {
+		t.Errorf("ParseRecipe() ingredients = %q, want %q", rawRecipe.IngredientDescriptions, want)
+	}
+	if want := []string{"Whisk everything together.", "Fry in a hot pan."}; !reflect.DeepEqual(rawRecipe.Steps, want) {
+		t.Errorf("ParseRecipe() steps = %q, want %q", rawRecipe.Steps, want)
+	}
+	metadata := rawRecipe.Metadata
+	if metadata.Servings.Max != 4 || metadata.MinutesTotal != 30 {
+		t.Errorf("ParseRecipe() servings, total time = %+v, %d, want 4 and 30", metadata.Servings, metadata.MinutesTotal)
+	}
+	if want := []string{"Breakfast recipes", "Recipes"}; !reflect.DeepEqual(metadata.Tags, want) {
+		t.Errorf("ParseRecipe() tags = %q, want %q", metadata.Tags, want)
+	}
+
+	// A page without a procedure is not a recipe.
+	noSteps := strings.Replace(testWikibooksPage, "Procedure", "History", 1)
+	node, _ = html.Parse(strings.NewReader(noSteps))
+	if _, err := NewWikibooksParser().ParseRecipe(node); err == nil {
+		t.Error("ParseRecipe() of a page without steps = nil, want an error")
+	}
+}
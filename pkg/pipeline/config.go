@@ -0,0 +1,188 @@
+package pipeline
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/cleaner"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/processor"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/prompter"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/scraper"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes every stage of the pipeline. It is read from a YAML or JSON file,
+// any field left out keeps its default value.
+type Config struct {
+	Scrape      ScrapeConfig    `yaml:"scrape"`
+	Clean       CleanConfig     `yaml:"clean"`
+	Ingredients ProcessConfig   `yaml:"process_ingredients"`
+	Attributes  ProcessConfig   `yaml:"process_attributes"`
+	Prompter    prompter.Config `yaml:"prompter"`
+}
+
+// ScrapeConfig configures the scraping stage.
+type ScrapeConfig struct {
+	Skip bool `yaml:"skip"`
+
+	OutputPath       string `yaml:"output_path"`
+	LinksPath        string `yaml:"links_path"`
+	SkippedLinksPath string `yaml:"skipped_links_path"`
+	UserAgent        string `yaml:"user_agent"`
+
+	// RequiredFields are the fields a recipe is not kept without, a recipe missing any
+	// other field is kept with what could be parsed. The fields each recipe is missing
+	// are recorded in DiagnosticsPath.
+	RequiredFields  []string `yaml:"required_fields"`
+	DiagnosticsPath string   `yaml:"diagnostics_path"`
+
+	// StateDir holds the crawl state of every source. A scrape with existing crawl
+	// state resumes it, unless Fresh is set.
+	StateDir    string `yaml:"state_dir"`
+	Fresh       bool   `yaml:"fresh"`
+	MaxAttempts int    `yaml:"max_attempts"`
+
+	// The HTTP client and throttle are shared by all sources, which are scraped in parallel.
+	scraper.HTTPConfig     `yaml:",inline"`
+	scraper.ThrottleConfig `yaml:",inline"`
+	scraper.RetryConfig    `yaml:",inline"`
+	// Drift is detected per source, a source whose parser stops finding a field fails the scrape.
+	scraper.DriftConfig `yaml:",inline"`
+
+	// Sites are YAML site definition files, or directories of them, that add sources or
+	// replace the parsers of built-in ones.
+	Sites   []string       `yaml:"sites"`
+	Sources []SourceConfig `yaml:"sources"`
+}
+
+// SourceConfig is a single site to scrape. Recipes are either found by crawling the
+// index pages from each start link, or read from a file of recipe links. If Type is
+// empty, every link is scraped by the source registered for its host, so a links file
+// may mix several sites.
+type SourceConfig struct {
+	Type       string   `yaml:"type"`
+	StartLinks []string `yaml:"start_links"`
+	LinksFile  string   `yaml:"links_file"`
+	OnlyLinks  bool     `yaml:"only_links"`
+	// ChangedSince only crawls the recipes of sources that crawl sitemaps whose sitemap
+	// entry changed on or after it, e.g. 2023-06-01.
+	ChangedSince time.Time `yaml:"changed_since"`
+	// Include and Exclude are regular expressions of the recipe links of sources that crawl
+	// sitemaps, e.g. "/recipes/" and "/videos/". Links that match no include pattern, or
+	// any exclude pattern, are left out.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// name names the source in logs and crawl state files.
+func (s SourceConfig) name() string {
+	if s.Type == "" {
+		return "mixed"
+	}
+	return s.Type
+}
+
+// statePath returns the crawl state file of the i-th source in dir. It is named after
+// what the source scrapes, its start links or its links file, so that scraping a source by
+// crawling it and from a links file do not resume each other's crawl.
+func (s SourceConfig) statePath(dir string, i int) string {
+	mode := "crawl"
+	switch {
+	case len(s.StartLinks) == 0:
+		mode = "links"
+	case s.LinksFile != "":
+		mode = "crawl-links"
+	}
+	key := crc32.ChecksumIEEE([]byte(strings.Join(append(append([]string{}, s.StartLinks...), s.LinksFile), "\n")))
+	return filepath.Join(dir, fmt.Sprintf("%d-%s-%s-%08x.jsonl", i, s.name(), mode, key))
+}
+
+// CleanConfig configures the cleaning stage. An empty input path uses the scrape output.
+type CleanConfig struct {
+	Skip           bool `yaml:"skip"`
+	cleaner.Config `yaml:",inline"`
+}
+
+// ProcessConfig configures a processing stage. An empty input path uses the output
+// of the previous stage.
+type ProcessConfig struct {
+	Skip bool `yaml:"skip"`
+
+	InputPath string `yaml:"input_path"`
+	// ExcludePath is a recipe file whose source URLs are not processed again.
+	ExcludePath string `yaml:"exclude_path"`
+	Workers     int    `yaml:"workers"`
+	// SampleSize is the number of randomly chosen recipes to process, 0 processes all.
+	SampleSize int `yaml:"sample_size"`
+
+	LogPath        string `yaml:"log_path"`
+	SuccessLogPath string `yaml:"success_log_path"`
+	OutputPath     string `yaml:"output_path"`
+}
+
+// DefaultConfig returns the configuration matching the paths the stages have always used.
+func DefaultConfig() *Config {
+	scraperDefaults := scraper.DefaultConfig()
+	processorDefaults := processor.DefaultConfig()
+	ingredients := ProcessConfig{
+		Workers:        3,
+		LogPath:        processorDefaults.LogPath,
+		SuccessLogPath: processorDefaults.SuccessLogPath,
+	}
+	attributes := ingredients
+	attributes.Workers = 2
+	attributes.OutputPath = "recipes/attr_proc_recipes.yaml"
+	cleanerDefaults := cleaner.DefaultConfig()
+	cleanerDefaults.OutputPath = "recipes/recipes.yaml"
+
+	return &Config{
+		Scrape: ScrapeConfig{
+			OutputPath:       scraperDefaults.OutputPath,
+			LinksPath:        scraperDefaults.LinksPath,
+			SkippedLinksPath: scraperDefaults.SkippedLinksPath,
+			UserAgent:        scraperDefaults.UserAgent,
+			RequiredFields:   scraperDefaults.RequiredFields,
+			DiagnosticsPath:  scraperDefaults.DiagnosticsPath,
+			StateDir:         "state",
+			MaxAttempts:      scraperDefaults.MaxAttempts,
+			HTTPConfig:       scraperDefaults.HTTPConfig,
+			ThrottleConfig:   scraperDefaults.ThrottleConfig,
+			RetryConfig:      scraperDefaults.RetryConfig,
+			DriftConfig:      scraperDefaults.DriftConfig,
+		},
+		Clean:       CleanConfig{Config: cleanerDefaults},
+		Ingredients: ingredients,
+		Attributes:  attributes,
+		Prompter:    prompter.DefaultConfig(),
+	}
+}
+
+// LoadConfig reads a pipeline configuration from a YAML or JSON file on top of the defaults.
+func LoadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open pipeline config: %w", err)
+	}
+	defer file.Close()
+
+	// JSON is valid YAML, so one decoder handles both formats.
+	cfg := DefaultConfig()
+	if err := yaml.NewDecoder(file).Decode(cfg); err != nil {
+		return nil, fmt.Errorf("could not decode pipeline config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// processorConfig returns the processor configuration of a processing stage.
+func (c *Config) processorConfig(stage ProcessConfig) processor.Config {
+	return processor.Config{
+		LogPath:        stage.LogPath,
+		SuccessLogPath: stage.SuccessLogPath,
+		OutputPath:     stage.OutputPath,
+		Prompter:       c.Prompter,
+	}
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigExample(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join("..", "..", "pipeline.example.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	var types []string
+	for _, source := range cfg.Scrape.Sources {
+		types = append(types, source.Type)
+	}
+	if want := []string{"foodnetwork", "wikibooks"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("LoadConfig() of the example has sources %q, want %q", types, want)
+	}
+	if cfg.Attributes.Workers != 2 || cfg.Prompter != DefaultConfig().Prompter {
+		t.Errorf("LoadConfig() of the example = %+v, %+v, want 2 workers and the default prompter", cfg.Attributes, cfg.Prompter)
+	}
+}
+
+func TestLoadConfigKeepsDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"pipeline.yaml", "scrape:\n  output_path: out.yaml\nprocess_ingredients:\n  workers: 7\n"},
+		{"pipeline.json", `{"scrape": {"output_path": "out.yaml"}, "process_ingredients": {"workers": 7}}`},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), test.name)
+		if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Errorf("%s: LoadConfig() = %v", test.name, err)
+			continue
+		}
+
+		want := DefaultConfig()
+		want.Scrape.OutputPath = "out.yaml"
+		want.Ingredients.Workers = 7
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("%s: LoadConfig() = %+v, want %+v", test.name, cfg, want)
+		}
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadConfig() of a missing file = nil, want an error")
+	}
+}
+
+func TestSampleSize(t *testing.T) {
+	tests := []struct {
+		requested int
+		available int
+		want      int
+	}{
+		{0, 10, 10},
+		{-1, 10, 10},
+		{3, 10, 3},
+		{20, 10, 10},
+		{5, 0, 0},
+	}
+	for _, test := range tests {
+		if got := sampleSize(test.requested, test.available); got != test.want {
+			t.Errorf("sampleSize(%d, %d) = %d, want %d", test.requested, test.available, got, test.want)
+		}
+	}
+}
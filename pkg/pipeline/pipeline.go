@@ -0,0 +1,367 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/cleaner"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/processor"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/scraper"
+	"gopkg.in/yaml.v3"
+)
+
+// Run runs every stage of the pipeline that is not skipped, feeding the output of
+// each stage into the next.
+func Run(ctx context.Context, cfg *Config) error {
+	if !cfg.Scrape.Skip {
+		if err := RunScrape(ctx, cfg); err != nil {
+			return fmt.Errorf("scrape stage: %w", err)
+		}
+	}
+
+	if !cfg.Clean.Skip {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := RunClean(cfg); err != nil {
+			return fmt.Errorf("clean stage: %w", err)
+		}
+	}
+
+	if !cfg.Ingredients.Skip {
+		outputPath, err := RunProcessIngredients(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("process ingredients stage: %w", err)
+		}
+		cfg.Ingredients.OutputPath = outputPath
+	}
+
+	if !cfg.Attributes.Skip {
+		if _, err := RunProcessAttributes(ctx, cfg); err != nil {
+			return fmt.Errorf("process attributes stage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RunScrape scrapes every configured source into the scrape output file.
+func RunScrape(ctx context.Context, cfg *Config) error {
+	if len(cfg.Scrape.Sources) == 0 {
+		return fmt.Errorf("no sources configured")
+	}
+
+	policy := parser.FieldPolicy{Required: cfg.Scrape.RequiredFields}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if cfg.Scrape.DriftWindow > 0 {
+		if err := cfg.Scrape.DriftConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	sources := scraper.DefaultSources.Clone()
+	if err := sources.LoadSites(cfg.Scrape.Sites); err != nil {
+		return err
+	}
+	for i, source := range cfg.Scrape.Sources {
+		if source.Type == "" {
+			continue
+		}
+		if _, err := sources.Lookup(source.Type); err != nil {
+			return fmt.Errorf("source %d: %w", i, err)
+		}
+	}
+
+	statePaths := make([]string, len(cfg.Scrape.Sources))
+	for i, source := range cfg.Scrape.Sources {
+		statePaths[i] = source.statePath(cfg.Scrape.StateDir, i)
+	}
+	if cfg.Scrape.Fresh {
+		if err := removeCrawlState(statePaths); err != nil {
+			return err
+		}
+	}
+
+	// The output and links files are shared by all sources, so truncate them once
+	// and let every scraper append. A resumed crawl keeps what it has so far.
+	resume, err := hasCrawlState(statePaths)
+	if err != nil {
+		return err
+	}
+	if resume {
+		log.Println("Resuming crawl from state in " + cfg.Scrape.StateDir)
+	} else {
+		for _, path := range []string{cfg.Scrape.OutputPath, cfg.Scrape.LinksPath, cfg.Scrape.SkippedLinksPath, cfg.Scrape.DiagnosticsPath} {
+			if path == "" {
+				continue
+			}
+			if err := truncate(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	client, err := scraper.NewHTTPClient(cfg.Scrape.HTTPConfig, nil)
+	if err != nil {
+		return err
+	}
+	scraperCfg := scraper.Config{
+		Sources:          sources,
+		OutputPath:       cfg.Scrape.OutputPath,
+		LinksPath:        cfg.Scrape.LinksPath,
+		SkippedLinksPath: cfg.Scrape.SkippedLinksPath,
+		UserAgent:        cfg.Scrape.UserAgent,
+		RequiredFields:   cfg.Scrape.RequiredFields,
+		DiagnosticsPath:  cfg.Scrape.DiagnosticsPath,
+		ParseStats:       scraper.NewParseStats(),
+		MaxAttempts:      cfg.Scrape.MaxAttempts,
+		AppendOutput:     true,
+		HTTPConfig:       cfg.Scrape.HTTPConfig,
+		Client:           client,
+		RetryConfig:      cfg.Scrape.RetryConfig,
+		DriftConfig:      cfg.Scrape.DriftConfig,
+		Throttle:         scraper.NewThrottle(cfg.Scrape.ThrottleConfig),
+	}
+	errs := make([]error, len(cfg.Scrape.Sources))
+	wg := sync.WaitGroup{}
+	for i, source := range cfg.Scrape.Sources {
+		wg.Add(1)
+		go func(i int, source SourceConfig) {
+			defer wg.Done()
+			errs[i] = scrapeSource(ctx, scraperCfg, source, statePaths[i])
+		}(i, source)
+	}
+	wg.Wait()
+	scraperCfg.ParseStats.Log()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("source %d (%s): %w", i, cfg.Scrape.Sources[i].name(), err)
+		}
+	}
+
+	// Only an interrupted or failed crawl is resumed, the next run of a finished one
+	// starts over.
+	log.Println("Crawl finished, removing its crawl state")
+	return removeCrawlState(statePaths)
+}
+
+// scrapeSource scrapes the start links and links file of a single source, with the
+// settings of scraperCfg that all sources share.
+func scrapeSource(ctx context.Context, scraperCfg scraper.Config, source SourceConfig, statePath string) error {
+	scraperCfg.SourceType = source.Type
+	scraperCfg.OnlyLinks = source.OnlyLinks
+	scraperCfg.StatePath = statePath
+	scraperCfg.ChangedSince = source.ChangedSince
+	scraperCfg.Include = source.Include
+	scraperCfg.Exclude = source.Exclude
+
+	for _, startLink := range source.StartLinks {
+		scraperCfg.StartLink = startLink
+		s, err := scraper.NewScraper(scraperCfg)
+		if err != nil {
+			return err
+		}
+		if err := s.Scrape(ctx); err != nil {
+			return err
+		}
+	}
+
+	if source.LinksFile != "" {
+		s, err := scraper.NewScraper(scraperCfg)
+		if err != nil {
+			return err
+		}
+		if err := s.ScrapeFromLinksFile(ctx, source.LinksFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunClean removes blank and duplicate recipes. An empty input path uses the scrape output.
+func RunClean(cfg *Config) error {
+	inPath := cfg.Clean.InputPath
+	if inPath == "" {
+		inPath = cfg.Scrape.OutputPath
+	}
+	if cfg.Clean.OutputPath == "" {
+		return fmt.Errorf("no clean output path configured")
+	}
+
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Clean.OutputPath), 0755); err != nil {
+		return err
+	}
+	outFile, err := os.Create(cfg.Clean.OutputPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	c := cleaner.NewFileCleaner(inFile, outFile)
+	c.RemoveBlankImages = cfg.Clean.RemoveBlankImages
+	c.RemoveBlankDescriptions = cfg.Clean.RemoveBlankDescriptions
+	if err := c.Clean(); err != nil {
+		return fmt.Errorf("error cleaning: %w", err)
+	}
+	return nil
+}
+
+// RunProcessIngredients parses the ingredients of the cleaned recipes and returns the
+// path of the processed recipe file. An empty input path uses the clean output.
+func RunProcessIngredients(ctx context.Context, cfg *Config) (string, error) {
+	stage := cfg.Ingredients
+	if stage.Workers < 1 {
+		return "", fmt.Errorf("workers must be at least 1")
+	}
+	inPath := stage.InputPath
+	if inPath == "" {
+		inPath = cfg.Clean.OutputPath
+	}
+
+	recipes := make([]*recipe.RawRecipe, 0)
+	if err := ReadYAML(inPath, &recipes); err != nil {
+		return "", err
+	}
+
+	if stage.ExcludePath != "" {
+		filterBase := make([]*recipe.RawRecipe, 0)
+		if err := ReadYAML(stage.ExcludePath, &filterBase); err != nil {
+			return "", err
+		}
+		urlSet := make(map[string]bool)
+		for _, r := range filterBase {
+			urlSet[r.Metadata.SourceURL] = true
+		}
+
+		filtered := make([]*recipe.RawRecipe, 0, len(recipes))
+		for _, r := range recipes {
+			if !urlSet[r.Metadata.SourceURL] {
+				filtered = append(filtered, r)
+			}
+		}
+		recipes = filtered
+	}
+
+	p, err := processor.NewRecipeProcessor(cfg.processorConfig(stage))
+	if err != nil {
+		return "", err
+	}
+	defer p.Close()
+
+	log.Printf("Processing ingredients of %s into %s", inPath, p.OutputPath())
+	n := sampleSize(stage.SampleSize, len(recipes))
+	return p.OutputPath(), p.ProcessRawRecipes(ctx, recipes, n, stage.Workers)
+}
+
+// RunProcessAttributes tags processed recipes with dietary attributes and returns the
+// path of the tagged recipe file. An empty input path uses the ingredient stage output.
+func RunProcessAttributes(ctx context.Context, cfg *Config) (string, error) {
+	stage := cfg.Attributes
+	if stage.Workers < 1 {
+		return "", fmt.Errorf("workers must be at least 1")
+	}
+	inPath := stage.InputPath
+	if inPath == "" {
+		inPath = cfg.Ingredients.OutputPath
+	}
+	if inPath == "" {
+		return "", fmt.Errorf("no input path configured")
+	}
+
+	recipes := make([]*recipe.Recipe, 0)
+	if err := ReadYAML(inPath, &recipes); err != nil {
+		return "", err
+	}
+
+	groupedByUrl := make(map[string][]*recipe.Recipe)
+	for _, r := range recipes {
+		groupedByUrl[r.Metadata.SourceURL] = append(groupedByUrl[r.Metadata.SourceURL], r)
+	}
+
+	p, err := processor.NewRecipeProcessor(cfg.processorConfig(stage))
+	if err != nil {
+		return "", err
+	}
+	defer p.Close()
+
+	log.Printf("Processing attributes of %s into %s", inPath, p.OutputPath())
+	n := sampleSize(stage.SampleSize, len(groupedByUrl))
+	return p.OutputPath(), p.ProcessRecipeAttributes(ctx, groupedByUrl, n, stage.Workers)
+}
+
+// ReadYAML decodes the YAML file at path into out.
+func ReadYAML(path string, out any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := yaml.NewDecoder(file).Decode(out); err != nil {
+		return fmt.Errorf("could not decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// hasCrawlState reports whether any of paths holds the crawl state of an earlier scrape.
+func hasCrawlState(paths []string) (bool, error) {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not read crawl state: %w", err)
+		}
+		if info.Size() > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// removeCrawlState removes the crawl state files at paths that exist.
+func removeCrawlState(paths []string) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove crawl state: %w", err)
+		}
+	}
+	return nil
+}
+
+// truncate creates an empty file at path, along with its parent directories.
+func truncate(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// sampleSize clamps a requested sample size to the number of available items.
+// A non-positive request means all items.
+func sampleSize(requested, available int) int {
+	if requested <= 0 || requested > available {
+		return available
+	}
+	return requested
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/scraper"
+)
+
+// testScrapeConfig returns a config that scrapes the links file of a JSON-LD source into
+// dir without throttling.
+func testScrapeConfig(dir, linksFile string) *Config {
+	cfg := DefaultConfig()
+	cfg.Scrape.OutputPath = filepath.Join(dir, "recipes.yaml")
+	cfg.Scrape.LinksPath = filepath.Join(dir, "links.tmp")
+	cfg.Scrape.SkippedLinksPath = filepath.Join(dir, "skippedLinks.txt")
+	cfg.Scrape.DiagnosticsPath = ""
+	cfg.Scrape.StateDir = filepath.Join(dir, "state")
+	cfg.Scrape.ThrottleConfig = scraper.ThrottleConfig{Concurrency: 2, RequestsPerSecond: 1000, Burst: 100}
+	cfg.Scrape.DriftWindow = 0
+	cfg.Scrape.Sources = []SourceConfig{{Type: "jsonld", LinksFile: linksFile}}
+	return cfg
+}
+
+func TestSourceConfigStatePath(t *testing.T) {
+	crawl := SourceConfig{Type: "foodnetwork", StartLinks: []string{"https://www.foodnetwork.com/recipes/recipes-a-z/123"}}
+	links := SourceConfig{Type: "foodnetwork", LinksFile: "links.tmp"}
+	otherCrawl := SourceConfig{Type: "foodnetwork", StartLinks: []string{"https://www.foodnetwork.com/recipes/recipes-a-z/a"}}
+
+	if crawl.statePath("state", 0) != crawl.statePath("state", 0) {
+		t.Error("the state path of a source changes between runs")
+	}
+	paths := map[string]string{}
+	for name, path := range map[string]string{
+		"crawl":        crawl.statePath("state", 0),
+		"links":        links.statePath("state", 0),
+		"other crawl":  otherCrawl.statePath("state", 0),
+		"second crawl": crawl.statePath("state", 1),
+	} {
+		if other, ok := paths[path]; ok {
+			t.Errorf("%s and %s share the crawl state %s", name, other, path)
+		}
+		paths[path] = name
+		if filepath.Dir(path) != "state" {
+			t.Errorf("%s: crawl state %s is not in the state directory", name, path)
+		}
+	}
+}
+
+func TestRunScrapeResumesOnlyInterruptedCrawls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/recipes/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `<html><head><script type="application/ld+json">
+{"@type": "Recipe", "name": %q, "recipeIngredient": ["1 egg"], "recipeInstructions": ["Fry the egg."]}
+</script></head></html>`, strings.TrimPrefix(r.URL.Path, "/recipes/"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	linksFile := filepath.Join(dir, "links.txt")
+	if err := os.WriteFile(linksFile, []byte(server.URL+"/recipes/eggs\n"+server.URL+"/recipes/toast\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testScrapeConfig(dir, linksFile)
+	statePath := cfg.Scrape.Sources[0].statePath(cfg.Scrape.StateDir, 0)
+	countRecipes := func() int {
+		output, err := os.ReadFile(cfg.Scrape.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(output), "name: ")
+	}
+
+	// An interrupted scrape keeps its crawl state.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := RunScrape(ctx, cfg); err == nil {
+		t.Fatal("RunScrape() with a cancelled context = nil, want an error")
+	}
+	if resume, err := hasCrawlState([]string{statePath}); err != nil || !resume {
+		t.Fatalf("hasCrawlState() after an interrupted scrape = %t, %v, want true", resume, err)
+	}
+
+	// The next run resumes it, and removes the crawl state once it finishes.
+	if err := RunScrape(context.Background(), cfg); err != nil {
+		t.Fatalf("RunScrape() = %v", err)
+	}
+	if got := countRecipes(); got != 2 {
+		t.Errorf("resumed scrape wrote %d recipes, want 2", got)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("crawl state of a finished scrape was kept: %v", err)
+	}
+
+	// A finished scrape is not resumed, it starts over.
+	if err := RunScrape(context.Background(), cfg); err != nil {
+		t.Fatalf("RunScrape() = %v", err)
+	}
+	if got := countRecipes(); got != 2 {
+		t.Errorf("scrape after a finished one wrote %d recipes, want 2", got)
+	}
+}
@@ -1,9 +1,11 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
@@ -14,8 +16,31 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Config configures where a RecipeProcessor writes its logs and output, and how it prompts.
+type Config struct {
+	LogPath        string `yaml:"log_path"`
+	SuccessLogPath string `yaml:"success_log_path"`
+	// OutputPath is the file processed recipes are written to. If empty, a
+	// timestamped file in the recipes directory is used.
+	OutputPath string `yaml:"output_path"`
+
+	Prompter prompter.Config `yaml:"prompter"`
+}
+
+// DefaultConfig returns the processor configuration used when none is given.
+func DefaultConfig() Config {
+	return Config{
+		LogPath:        "logs/processor.log",
+		SuccessLogPath: "logs/processor_success.log",
+		Prompter:       prompter.DefaultConfig(),
+	}
+}
+
 type RecipeProcessor struct {
-	prompter    prompter.OpenAIPrompter
+	prompter        prompter.OpenAIPrompter
+	ingredientModel string
+	attributeModel  string
+
 	logFile     *os.File
 	successFile *os.File
 	outputFile  *os.File
@@ -66,37 +91,67 @@ func (p *RecipeProcessor) writeOutput(recipeOut *recipe.Recipe) {
 	}
 }
 
-func NewRecipeProcessor() *RecipeProcessor {
-	logFile, err := os.Create("logs/processor.log")
+func NewRecipeProcessor(cfg Config) (*RecipeProcessor, error) {
+	openai, err := prompter.NewOpenAIPrompter(cfg.Prompter)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	successFile, err := os.OpenFile("logs/processor_success.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		panic(err)
+
+	outputPath := cfg.OutputPath
+	if outputPath == "" {
+		timeStamp := time.Now().Format("2006-01-02-15-04-05")
+		outputPath = fmt.Sprintf("recipes/ing_proc_recipes_%s.yaml", timeStamp)
+	}
+
+	for _, path := range []string{cfg.LogPath, cfg.SuccessLogPath, outputPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("could not create directory for %s: %w", path, err)
+		}
 	}
 
-	timeStamp := time.Now().Format("2006-01-02-15-04-05")
-	filename := fmt.Sprintf("recipes/ing_proc_recipes_%s.yaml", timeStamp)
-	outputFile, err := os.Create(filename)
+	logFile, err := os.Create(cfg.LogPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not create log file: %w", err)
+	}
+	successFile, err := os.OpenFile(cfg.SuccessLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		panic(err)
+		logFile.Close()
+		return nil, fmt.Errorf("could not open success log file: %w", err)
+	}
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		logFile.Close()
+		successFile.Close()
+		return nil, fmt.Errorf("could not create output file: %w", err)
 	}
 
 	return &RecipeProcessor{
-		prompter:    *prompter.NewOpenAIPrompter(),
-		logFile:     logFile,
-		successFile: successFile,
-		outputFile:  outputFile,
-	}
+		prompter:        *openai,
+		ingredientModel: cfg.Prompter.IngredientModel,
+		attributeModel:  cfg.Prompter.AttributeModel,
+		logFile:         logFile,
+		successFile:     successFile,
+		outputFile:      outputFile,
+	}, nil
+}
+
+// OutputPath returns the path of the file processed recipes are written to.
+func (p *RecipeProcessor) OutputPath() string {
+	return p.outputFile.Name()
 }
 
+// Close flushes and closes the log and output files.
 func (p *RecipeProcessor) Close() {
 	p.logFile.Close()
 	p.successFile.Close()
+	p.outputFile.Sync()
+	p.outputFile.Close()
 }
 
-func (p *RecipeProcessor) ProcessRawRecipes(recipes []*recipe.RawRecipe, n int, workers int) error {
+// ProcessRawRecipes parses the ingredients of n randomly chosen recipes with a pool of
+// workers. It stops handing out recipes once ctx is done, waits for the workers to
+// finish and returns the context's error.
+func (p *RecipeProcessor) ProcessRawRecipes(ctx context.Context, recipes []*recipe.RawRecipe, n int, workers int) error {
 	recipeChan := make(chan *recipe.RawRecipe, n)
 
 	rand.Shuffle(len(recipes), func(i, j int) { recipes[i], recipes[j] = recipes[j], recipes[i] })
@@ -107,15 +162,23 @@ func (p *RecipeProcessor) ProcessRawRecipes(recipes []*recipe.RawRecipe, n int,
 
 	// producer
 	go func() {
+		defer close(recipeChan)
 		for _, recipeIn := range recipes {
-			recipeChan <- recipeIn
+			select {
+			case recipeChan <- recipeIn:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
 	for i := 0; i < workers; i++ {
 		go func(i int) {
 			for recipeIn := range recipeChan {
-				processedRecipes, err := p.ProcessRecipe(recipeIn, i)
+				if ctx.Err() != nil {
+					continue
+				}
+				processedRecipes, err := p.ProcessRecipe(ctx, recipeIn, i)
 				if err != nil {
 					p.writeErr(recipeIn, err, i)
 					continue
@@ -132,10 +195,13 @@ func (p *RecipeProcessor) ProcessRawRecipes(recipes []*recipe.RawRecipe, n int,
 		}(i)
 	}
 	wg.Wait()
-	return nil
+	return ctx.Err()
 }
 
-func (p *RecipeProcessor) ProcessRecipeAttributes(recipes map[string][]*recipe.Recipe, n int, workers int) error {
+// ProcessRecipeAttributes tags n groups of recipes with dietary attributes with a pool of
+// workers. It stops handing out groups once ctx is done, waits for the workers to
+// finish and returns the context's error.
+func (p *RecipeProcessor) ProcessRecipeAttributes(ctx context.Context, recipes map[string][]*recipe.Recipe, n int, workers int) error {
 	recipeChan := make(chan []*recipe.Recipe, n)
 
 	wg := sync.WaitGroup{}
@@ -143,15 +209,28 @@ func (p *RecipeProcessor) ProcessRecipeAttributes(recipes map[string][]*recipe.R
 
 	// producer
 	go func() {
+		defer close(recipeChan)
+		sent := 0
 		for _, recipeIn := range recipes {
-			recipeChan <- recipeIn
+			if sent == n {
+				break
+			}
+			select {
+			case recipeChan <- recipeIn:
+			case <-ctx.Done():
+				return
+			}
+			sent++
 		}
 	}()
 
 	for i := 0; i < workers; i++ {
 		go func(i int) {
 			for recipeIn := range recipeChan {
-				processedRecipes, err := p.ProcessAttributes(recipeIn, i)
+				if ctx.Err() != nil {
+					continue
+				}
+				processedRecipes, err := p.ProcessAttributes(ctx, recipeIn, i)
 				if err != nil {
 					p.writeRecErr(recipeIn[0], err, i)
 					continue
@@ -168,10 +247,10 @@ func (p *RecipeProcessor) ProcessRecipeAttributes(recipes map[string][]*recipe.R
 		}(i)
 	}
 	wg.Wait()
-	return nil
+	return ctx.Err()
 }
 
-func (p *RecipeProcessor) ProcessAttributes(groupedByUrl []*recipe.Recipe, workerNum int) ([]*recipe.Recipe, error) {
+func (p *RecipeProcessor) ProcessAttributes(ctx context.Context, groupedByUrl []*recipe.Recipe, workerNum int) ([]*recipe.Recipe, error) {
 	fmt.Println("Processing attributes for " + groupedByUrl[0].Name)
 	combinedIng := make(map[string]bool)
 	for _, recipe := range groupedByUrl {
@@ -194,8 +273,8 @@ func (p *RecipeProcessor) ProcessAttributes(groupedByUrl []*recipe.Recipe, worke
 
 	p.writeMsg(fmt.Sprintf("%d: Input:\n%s", workerNum, ingredientsStr))
 
-	request := prompter.NewIngredientAttributeRequest(ingredientsStr)
-	resp, err := p.prompter.MakeRequest(request)
+	request := prompter.NewIngredientAttributeRequest(ingredientsStr, p.attributeModel)
+	resp, err := p.prompter.MakeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -253,16 +332,16 @@ func (p *RecipeProcessor) ProcessAttributes(groupedByUrl []*recipe.Recipe, worke
 	return out, nil
 }
 
-func (p *RecipeProcessor) ProcessRecipe(recipeIn *recipe.RawRecipe, workerNum int) ([]*recipe.Recipe, error) {
+func (p *RecipeProcessor) ProcessRecipe(ctx context.Context, recipeIn *recipe.RawRecipe, workerNum int) ([]*recipe.Recipe, error) {
 	fmt.Printf("Processing: %s, %s\n", recipeIn.Name, recipeIn.Metadata.SourceURL)
-	ingredients := p.reorderIngredients(recipeIn.IngredientDescriptions)
+	ingredients, groups := p.reorderIngredients(recipeIn.IngredientDescriptions, recipeIn.IngredientGroupNames())
 	ingredientsStr := ""
 	for _, ingredient := range ingredients {
 		ingredientsStr += ingredient + "\n"
 	}
 
-	request := prompter.NewParseIngredientsRequest(ingredientsStr)
-	resp, err := p.prompter.MakeRequest(request)
+	request := prompter.NewParseIngredientsRequest(ingredientsStr, p.ingredientModel)
+	resp, err := p.prompter.MakeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -371,8 +450,16 @@ func (p *RecipeProcessor) ProcessRecipe(recipeIn *recipe.RawRecipe, workerNum in
 				unit = recipe.UnitFraction
 			}
 
+			// The index of an ingredient is its line in the request, which keeps the
+			// ingredient in its group.
+			group := ""
+			if line, _ := getIndex(parsedIngredients[index]); groups != nil && line >= 1 && line <= len(groups) {
+				group = groups[line-1]
+			}
+
 			ingredients[i] = recipe.IngredientItem{
-				Name: parsedIngredients[index].Ingredient,
+				Group: group,
+				Name:  parsedIngredients[index].Ingredient,
 				Amount: recipe.Amount{
 					Value:    frac,
 					Type:     unit,
@@ -385,6 +472,7 @@ func (p *RecipeProcessor) ProcessRecipe(recipeIn *recipe.RawRecipe, workerNum in
 
 		recipeResult := recipeIn.ToRecipe()
 		recipeResult.Ingredients = ingredients
+		recipeResult.StepAnnotations = recipeResult.AnnotateSteps()
 		recipeResult.Metadata.Dietary = variantDietary
 
 		recipeOut = append(recipeOut, recipeResult)
@@ -456,7 +544,13 @@ func (p *RecipeProcessor) parseDietary(s string) (map[string][]string, error) {
 	return dietary, nil
 }
 
-func (p *RecipeProcessor) reorderIngredients(ingredients []string) []string {
+// reorderIngredients returns a copy of the ingredients with the ones that have options
+// first, and the names of their groups in the same order. groups may be nil.
+func (p *RecipeProcessor) reorderIngredients(ingredients []string, groups []string) ([]string, []string) {
+	ingredients = append([]string(nil), ingredients...)
+	if groups != nil {
+		groups = append([]string(nil), groups...)
+	}
 	i := 0
 	j := len(ingredients) - 1
 	for i < j {
@@ -464,13 +558,16 @@ func (p *RecipeProcessor) reorderIngredients(ingredients []string) []string {
 			i += 1
 		} else if strings.Contains(ingredients[j], " or ") {
 			ingredients[i], ingredients[j] = ingredients[j], ingredients[i]
+			if groups != nil {
+				groups[i], groups[j] = groups[j], groups[i]
+			}
 			i += 1
 			j -= 1
 		} else {
 			j -= 1
 		}
 	}
-	return ingredients
+	return ingredients, groups
 }
 
 // creates a list of slices of indices for each ingredient
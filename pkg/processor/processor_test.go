@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/prompter"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+)
+
+// newTestProcessor creates a processor that logs and writes to a temporary directory.
+func newTestProcessor(t *testing.T) *RecipeProcessor {
+	dir := t.TempDir()
+	p, err := NewRecipeProcessor(Config{
+		LogPath:        filepath.Join(dir, "processor.log"),
+		SuccessLogPath: filepath.Join(dir, "processor_success.log"),
+		OutputPath:     filepath.Join(dir, "recipes.yaml"),
+		Prompter:       prompter.Config{Token: "token"},
+	})
+	if err != nil {
+		t.Fatalf("NewRecipeProcessor() = %v", err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+// finishes runs process and fails the test if it does not return within a second.
+func finishes(t *testing.T, name string, process func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- process() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("%s did not return", name)
+		return nil
+	}
+}
+
+func TestProcessCancelled(t *testing.T) {
+	p := newTestProcessor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rawRecipes := make([]*recipe.RawRecipe, 20)
+	for i := range rawRecipes {
+		rawRecipes[i] = &recipe.RawRecipe{Name: "Pancakes", IngredientDescriptions: []string{"1 egg"}}
+	}
+	err := finishes(t, "ProcessRawRecipes()", func() error {
+		return p.ProcessRawRecipes(ctx, rawRecipes, len(rawRecipes), 3)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessRawRecipes() with a cancelled context = %v, want context.Canceled", err)
+	}
+
+	grouped := map[string][]*recipe.Recipe{
+		"a": {{Name: "Pancakes"}},
+		"b": {{Name: "Waffles"}},
+	}
+	err = finishes(t, "ProcessRecipeAttributes()", func() error {
+		return p.ProcessRecipeAttributes(ctx, grouped, len(grouped), 2)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessRecipeAttributes() with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestProcessNothing(t *testing.T) {
+	p := newTestProcessor(t)
+	// The workers stop once every recipe is handed out, even if there are none.
+	err := finishes(t, "ProcessRawRecipes()", func() error {
+		return p.ProcessRawRecipes(context.Background(), []*recipe.RawRecipe{{Name: "Pancakes"}}, 0, 3)
+	})
+	if err != nil {
+		t.Errorf("ProcessRawRecipes() of no recipes = %v", err)
+	}
+	err = finishes(t, "ProcessRecipeAttributes()", func() error {
+		return p.ProcessRecipeAttributes(context.Background(), map[string][]*recipe.Recipe{"a": {{Name: "Pancakes"}}}, 0, 2)
+	})
+	if err != nil {
+		t.Errorf("ProcessRecipeAttributes() of no recipes = %v", err)
+	}
+}
@@ -2,15 +2,38 @@ package prompter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 )
 
+// Config configures an OpenAIPrompter.
+type Config struct {
+	// TokenPath is a file containing the OpenAI API token. It is only read if Token is empty.
+	TokenPath string `yaml:"token_path"`
+	Token     string `yaml:"token"`
+
+	// IngredientModel and AttributeModel override the models used for the
+	// ingredient parsing and ingredient attribute requests.
+	IngredientModel string `yaml:"ingredient_model"`
+	AttributeModel  string `yaml:"attribute_model"`
+}
+
+// DefaultConfig returns the prompter configuration used when none is given.
+func DefaultConfig() Config {
+	return Config{
+		TokenPath:       "secret/openai.token",
+		IngredientModel: parsingModel,
+		AttributeModel:  parsingModel,
+	}
+}
+
 type OpenAIPrompter struct {
 	token string
 }
@@ -29,45 +52,60 @@ type OpenAiResponse struct {
 	} `json:"usage"`
 }
 
-func NewOpenAIPrompter() *OpenAIPrompter {
-	token, err := os.ReadFile("secret/openai.token")
-	if err != nil {
-		panic(fmt.Errorf("failed to open openai token file: %w", err))
+func NewOpenAIPrompter(cfg Config) (*OpenAIPrompter, error) {
+	token := cfg.Token
+	if token == "" {
+		if cfg.TokenPath == "" {
+			return nil, fmt.Errorf("no openai token or token file configured")
+		}
+		tokenBytes, err := os.ReadFile(cfg.TokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open openai token file: %w", err)
+		}
+		token = string(tokenBytes)
 	}
 
 	return &OpenAIPrompter{
-		token: string(token),
-	}
+		token: strings.TrimSpace(token),
+	}, nil
 }
 
-func (p *OpenAIPrompter) MakeRequest(req OpenAIRequest) (*OpenAiResponse, error) {
+// MakeRequest sends req to OpenAI, backing off while it is rate limited. It gives up
+// when ctx is done or the backoff runs out.
+func (p *OpenAIPrompter) MakeRequest(ctx context.Context, req OpenAIRequest) (*OpenAiResponse, error) {
 	reqBody, err := req.MakeBody()
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request body: %w", err)
 	}
 
-	openAiReq, err := http.NewRequest(http.MethodPost, req.URL(), bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	openAiReq.Header.Add("Authorization", "Bearer "+p.token)
-	openAiReq.Header.Add("Content-Type", "application/json")
-
-	bOff := newBackoff()
+	bOff := backoff.WithContext(newBackoff(), ctx)
 	for {
-		resp, err := http.DefaultClient.Do(openAiReq)
+		resp, err := p.do(ctx, req.URL(), reqBody)
 		if err != nil {
 			return nil, fmt.Errorf("failed to make request: %w", err)
 		}
 
-		defer resp.Body.Close()
 		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
 			timeWait := bOff.NextBackOff()
+			if timeWait == backoff.Stop {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, fmt.Errorf("bad status code: %s, giving up", resp.Status)
+			}
 			fmt.Printf("Too many requests, waiting %f seconds\n", timeWait.Seconds())
-			time.Sleep(timeWait)
+
+			timer := time.NewTimer(timeWait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			}
 			continue
 		}
+		defer resp.Body.Close()
 		if resp.StatusCode != http.StatusOK {
 			return nil, fmt.Errorf("bad status code: %s", resp.Status)
 		}
@@ -82,6 +120,19 @@ func (p *OpenAIPrompter) MakeRequest(req OpenAIRequest) (*OpenAiResponse, error)
 	}
 }
 
+// do makes a single POST request with a JSON body. A new request is made for every
+// attempt, since the body reader is used up by the previous one.
+func (p *OpenAIPrompter) do(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	openAiReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	openAiReq.Header.Add("Authorization", "Bearer "+p.token)
+	openAiReq.Header.Add("Content-Type", "application/json")
+	return http.DefaultClient.Do(openAiReq)
+}
+
 func newBackoff() backoff.BackOff {
 	bOff := backoff.NewExponentialBackOff()
 	bOff.MaxElapsedTime = time.Minute
@@ -0,0 +1,91 @@
+package prompter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testRequest is a request to a test server.
+type testRequest struct {
+	url string
+}
+
+func (r testRequest) MakeBody() ([]byte, error) {
+	return []byte(`{"input": "1 egg"}`), nil
+}
+
+func (r testRequest) URL() string {
+	return r.url
+}
+
+func TestNewOpenAIPrompterToken(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "openai.token")
+	if err := os.WriteFile(tokenPath, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cfg     Config
+		want    string
+		wantErr bool
+	}{
+		{Config{Token: " config-token ", TokenPath: tokenPath}, "config-token", false},
+		{Config{TokenPath: tokenPath}, "file-token", false},
+		{Config{TokenPath: filepath.Join(t.TempDir(), "missing.token")}, "", true},
+		{Config{}, "", true},
+	}
+	for _, test := range tests {
+		p, err := NewOpenAIPrompter(test.cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("NewOpenAIPrompter(%+v) error = %v, want error %t", test.cfg, err, test.wantErr)
+			continue
+		}
+		if err == nil && p.token != test.want {
+			t.Errorf("NewOpenAIPrompter(%+v) token = %q, want %q", test.cfg, p.token, test.want)
+		}
+	}
+}
+
+func TestMakeRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"object": "edit", "choices": [{"text": "1,egg,1,qty,f,", "index": 0}]}`))
+		case "/rate-limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	p := &OpenAIPrompter{token: "token"}
+
+	resp, err := p.MakeRequest(context.Background(), testRequest{server.URL + "/ok"})
+	if err != nil || len(resp.Choices) != 1 || resp.Choices[0].Text != "1,egg,1,qty,f," {
+		t.Errorf("MakeRequest() = %+v, %v, want the choice of the response", resp, err)
+	}
+	if _, err := p.MakeRequest(context.Background(), testRequest{server.URL + "/error"}); err == nil {
+		t.Error("MakeRequest() of a failing server = nil, want an error")
+	}
+
+	// A rate limited request waits for the backoff, unless it is cancelled first.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.MakeRequest(ctx, testRequest{server.URL + "/rate-limited"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MakeRequest() of a rate limited request = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("MakeRequest() returned %s after it was cancelled", elapsed)
+	}
+}
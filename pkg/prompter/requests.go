@@ -66,17 +66,24 @@ type OpenAIRequest interface {
 
 type ParseIngredientsRequest struct {
 	ingredients string
+	model       string
 }
 
-func NewParseIngredientsRequest(ingredients string) *ParseIngredientsRequest {
+// NewParseIngredientsRequest creates a request to parse the ingredients with the given model.
+// An empty model uses the default parsing model.
+func NewParseIngredientsRequest(ingredients, model string) *ParseIngredientsRequest {
+	if model == "" {
+		model = parsingModel
+	}
 	return &ParseIngredientsRequest{
 		ingredients: ingredients,
+		model:       model,
 	}
 }
 
 func (r *ParseIngredientsRequest) MakeBody() ([]byte, error) {
 	reqData := map[string]any{
-		"model":       parsingModel,
+		"model":       r.model,
 		"instruction": parseIngredientDataInstruction,
 		"input":       r.ingredients,
 		"temperature": 0.1,
@@ -93,11 +100,18 @@ func (r *ParseIngredientsRequest) URL() string {
 
 type IngredientAttributeRequest struct {
 	ingredients string
+	model       string
 }
 
-func NewIngredientAttributeRequest(ingredients string) *IngredientAttributeRequest {
+// NewIngredientAttributeRequest creates a request to label the ingredients with the given model.
+// An empty model uses the default parsing model.
+func NewIngredientAttributeRequest(ingredients, model string) *IngredientAttributeRequest {
+	if model == "" {
+		model = parsingModel
+	}
 	return &IngredientAttributeRequest{
 		ingredients: ingredients,
+		model:       model,
 	}
 }
 
@@ -114,7 +128,7 @@ func (r *IngredientAttributeRequest) MakeBody() ([]byte, error) {
 	// }
 
 	reqData := map[string]any{
-		"model":       parsingModel,
+		"model":       r.model,
 		"instruction": ingredientAttributePrompt,
 		"input":       r.ingredients,
 		"temperature": 0.1,
@@ -0,0 +1,234 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OvernightMinutes is the inactive time "overnight" is taken to be.
+const OvernightMinutes = 8 * 60
+
+// REs for the ISO 8601 and free text durations of recipe times.
+var (
+	isoDurationRE = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	// durationRE matches an amount of a unit, e.g. "1 1/2 hours", "½ hr", "an hour" or
+	// "5 more minutes", and ranges of them, e.g. "20 to 25 min" or "1-2 days".
+	durationRE = regexp.MustCompile(`(?i)(` + durationNumber + `)(?:\s*(?:-|–|—|\bto\b|\bor\b)\s*(` + durationNumber + `))?\s*-?\s*(?:(?:more|additional|extra|further)\s+)?(days?|d|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+	// durationRangeRE splits a range of durations that each have units, e.g.
+	// "1 hour to 1 hour 30 minutes".
+	durationRangeRE = regexp.MustCompile(`(?i)\s+(?:to|or|-|–|—)\s+`)
+	// clockDurationRE matches durations written as h:mm, e.g. "1:30" or "0:45 hrs".
+	clockDurationRE = regexp.MustCompile(`(?i)^(\d+):([0-5]\d)(?:\s*(?:hours?|hrs?|h))?$`)
+	// trailingMinutesRE matches a number without a unit after hours, which are the minutes
+	// of e.g. "2 hours 30".
+	trailingMinutesRE = regexp.MustCompile(`(?i)^\s*(?:and\s+)?(\d+)\s*$`)
+	// inactiveRE matches the qualitative times that a dish is left alone for.
+	inactiveRE = regexp.MustCompile(`(?i)\bover\s?night\b`)
+)
+
+// durationNumber matches the numbers of free text durations.
+const durationNumber = `\d+(?:\.\d+)?(?:\s*[½¼¾]|\s+\d+/\d+)?|\d+/\d+|[½¼¾]|\bhalf(?:\s+an?)?\b|\ban?\b`
+
+// Duration is a recipe time, e.g. a prep or cook time.
+type Duration struct {
+	// Minutes is the active time. For ranges like "20 to 25 min" it is the longest time.
+	Minutes int
+	// MinMinutes is the shortest time of a range, or Minutes.
+	MinMinutes int
+	// InactiveMinutes is the time a dish is left alone for, like "overnight", which is not
+	// part of Minutes.
+	InactiveMinutes int
+}
+
+// Total returns the active and inactive minutes.
+func (d Duration) Total() int {
+	return d.Minutes + d.InactiveMinutes
+}
+
+// ErrInvalidDuration is returned for text that has no duration in it.
+type ErrInvalidDuration struct {
+	Text string
+}
+
+func (e ErrInvalidDuration) Error() string {
+	return fmt.Sprintf("no duration in %q", e.Text)
+}
+
+// ParseDuration parses an ISO 8601 duration like PT1H30M, or a free text one like
+// "1 hr 30 min", "1:30", "1½ hours", "20 to 25 minutes" or "10 minutes plus overnight".
+// Durations shorter than a minute are rounded up to one, and durations of no time at all
+// are invalid.
+func ParseDuration(text string) (Duration, error) {
+	text = strings.TrimSpace(text)
+	if seconds, ok := parseISOSeconds(text); ok {
+		return exactDuration(text, seconds/60)
+	}
+	if matches := clockDurationRE.FindStringSubmatch(text); matches != nil {
+		hours, _ := strconv.Atoi(matches[1])
+		minutes, _ := strconv.Atoi(matches[2])
+		return exactDuration(text, float64(hours*60+minutes))
+	}
+
+	var duration Duration
+	inactive := inactiveRE.MatchString(text)
+	if inactive {
+		duration.InactiveMinutes = OvernightMinutes
+		text = inactiveRE.ReplaceAllString(text, " ")
+	}
+
+	// A range of durations with their own units, e.g. "1 hour to 1 hour 30 minutes". In
+	// "1 hr 10 - 15 min" the first side ends without a unit, so it is a range of minutes.
+	if sides := durationRangeRE.Split(text, -1); len(sides) == 2 && endsWithDuration(sides[0]) {
+		min, _, minOK := sumDurations(sides[0])
+		_, max, maxOK := sumDurations(sides[1])
+		if minOK && maxOK {
+			if min > max {
+				min, max = max, min
+			}
+			duration.MinMinutes, duration.Minutes = roundMinutes(min), roundMinutes(max)
+			return duration, nil
+		}
+	}
+
+	min, max, ok := sumDurations(text)
+	if (!ok || max <= 0) && !inactive {
+		return Duration{}, ErrInvalidDuration{Text: text}
+	}
+	duration.MinMinutes, duration.Minutes = roundMinutes(min), roundMinutes(max)
+	return duration, nil
+}
+
+// exactDuration returns a duration of minutes, or an error if it is no time at all.
+func exactDuration(text string, minutes float64) (Duration, error) {
+	if minutes <= 0 {
+		return Duration{}, ErrInvalidDuration{Text: text}
+	}
+	return Duration{Minutes: roundMinutes(minutes), MinMinutes: roundMinutes(minutes)}, nil
+}
+
+// roundMinutes rounds minutes to whole minutes, rounding times shorter than a minute up so
+// that they are not lost.
+func roundMinutes(minutes float64) int {
+	if minutes > 0 && minutes < 1 {
+		return 1
+	}
+	return int(math.Round(minutes))
+}
+
+// sumDurations adds up the durations in text, e.g. "1 hr 10 to 15 min", into the shortest
+// and longest minutes. A number without a unit after hours is minutes, as in "2 hours 30".
+// ok is false if there is no duration in text.
+func sumDurations(text string) (min, max float64, ok bool) {
+	for _, matches := range durationRE.FindAllStringSubmatch(text, -1) {
+		low, lowOK := parseDurationNumber(matches[1])
+		high, highOK := low, lowOK
+		if matches[2] != "" {
+			high, highOK = parseDurationNumber(matches[2])
+		}
+		if !lowOK || !highOK {
+			continue
+		}
+		if low > high {
+			low, high = high, low
+		}
+
+		unit := durationUnitMinutes(matches[3])
+		min += low * unit
+		max += high * unit
+		ok = true
+	}
+
+	if indexes := durationRE.FindAllStringSubmatchIndex(text, -1); ok {
+		last := indexes[len(indexes)-1]
+		if durationUnitMinutes(text[last[6]:last[7]]) == 60 {
+			if matches := trailingMinutesRE.FindStringSubmatch(text[last[1]:]); matches != nil {
+				minutes, _ := strconv.ParseFloat(matches[1], 64)
+				min += minutes
+				max += minutes
+			}
+		}
+	}
+	return min, max, ok
+}
+
+// endsWithDuration returns whether text ends with a duration that has a unit.
+func endsWithDuration(text string) bool {
+	text = strings.TrimSpace(text)
+	matches := durationRE.FindAllStringIndex(text, -1)
+	return len(matches) > 0 && matches[len(matches)-1][1] == len(text)
+}
+
+// durationUnitMinutes returns the minutes of a unit matched by durationRE.
+func durationUnitMinutes(unit string) float64 {
+	switch unit = strings.ToLower(unit); {
+	case strings.HasPrefix(unit, "d"):
+		return 24 * 60
+	case strings.HasPrefix(unit, "h"):
+		return 60
+	case strings.HasPrefix(unit, "s"):
+		return 1.0 / 60
+	default:
+		return 1
+	}
+}
+
+// parseDurationNumber parses a number matched by durationNumber, like "1.5", "1 1/2", "1½",
+// "half an" or "a".
+func parseDurationNumber(text string) (float64, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	switch {
+	case strings.HasPrefix(text, "half"):
+		return 0.5, true
+	case text == "a" || text == "an":
+		return 1, true
+	}
+
+	text = strings.NewReplacer("½", " 1/2", "¼", " 1/4", "¾", " 3/4").Replace(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return value, true
+	}
+	value, _ := ParseFraction(text)
+	return value, value >= 0
+}
+
+// ParseISODuration returns an ISO 8601 duration like PT1H30M in minutes. ok is false if
+// text is not an ISO 8601 duration. Years and months are not used for recipes and are
+// ignored.
+func ParseISODuration(text string) (minutes int, ok bool) {
+	seconds, ok := parseISOSeconds(text)
+	return int(math.Round(seconds / 60)), ok
+}
+
+// ParseISODurationSeconds returns an ISO 8601 duration like PT1M30S in seconds, for
+// durations shorter than recipe times, like those of videos.
+func ParseISODurationSeconds(text string) (seconds int, ok bool) {
+	exact, ok := parseISOSeconds(text)
+	return int(math.Round(exact)), ok
+}
+
+// parseISOSeconds returns an ISO 8601 duration in seconds, ignoring years and months.
+func parseISOSeconds(text string) (float64, bool) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	matches := isoDurationRE.FindStringSubmatch(text)
+	if matches == nil || text == "P" || strings.HasSuffix(text, "T") {
+		return 0, false
+	}
+
+	secondsPer := []float64{0, 0, 7 * 24 * 60 * 60, 24 * 60 * 60, 60 * 60, 60, 1}
+	total := 0.0
+	for i, group := range matches[1:] {
+		if group == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(group, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += value * secondsPer[i]
+	}
+	return total, true
+}
@@ -0,0 +1,75 @@
+package recipe
+
+import "testing"
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		text string
+		want Duration
+	}{
+		{"PT1H30M", Duration{Minutes: 90, MinMinutes: 90}},
+		{"PT45M", Duration{Minutes: 45, MinMinutes: 45}},
+		{"P1DT2H", Duration{Minutes: 26 * 60, MinMinutes: 26 * 60}},
+		{"PT90S", Duration{Minutes: 2, MinMinutes: 2}},
+		{"1 hr 30 min", Duration{Minutes: 90, MinMinutes: 90}},
+		{"1 hour and 15 minutes", Duration{Minutes: 75, MinMinutes: 75}},
+		{"1½ hours", Duration{Minutes: 90, MinMinutes: 90}},
+		{"1 1/2 hours", Duration{Minutes: 90, MinMinutes: 90}},
+		{"3/4 hour", Duration{Minutes: 45, MinMinutes: 45}},
+		{"half an hour", Duration{Minutes: 30, MinMinutes: 30}},
+		{"an hour", Duration{Minutes: 60, MinMinutes: 60}},
+		{"20 to 25 minutes", Duration{Minutes: 25, MinMinutes: 20}},
+		{"20-25 mins", Duration{Minutes: 25, MinMinutes: 20}},
+		{"1 hr 10 - 15 min", Duration{Minutes: 75, MinMinutes: 70}},
+		{"1 hour to 1 hour 30 minutes", Duration{Minutes: 90, MinMinutes: 60}},
+		{"2 days", Duration{Minutes: 2 * 24 * 60, MinMinutes: 2 * 24 * 60}},
+		{"10 minutes plus overnight", Duration{Minutes: 10, MinMinutes: 10, InactiveMinutes: OvernightMinutes}},
+		{"Overnight", Duration{InactiveMinutes: OvernightMinutes}},
+		{"2 hours 30", Duration{Minutes: 150, MinMinutes: 150}},
+		{"1 hour and 15", Duration{Minutes: 75, MinMinutes: 75}},
+		{"1 hour to 1 hour 30", Duration{Minutes: 90, MinMinutes: 60}},
+		{"30 minutes 2", Duration{Minutes: 30, MinMinutes: 30}},
+		{"1:30", Duration{Minutes: 90, MinMinutes: 90}},
+		{"0:45 hrs", Duration{Minutes: 45, MinMinutes: 45}},
+		{"5 s", Duration{Minutes: 1, MinMinutes: 1}},
+		{"PT20S", Duration{Minutes: 1, MinMinutes: 1}},
+	}
+	for _, test := range tests {
+		got, err := ParseDuration(test.text)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) = %v", test.text, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseDuration(%q) = %+v, want %+v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseDurationInvalid(t *testing.T) {
+	for _, text := range []string{"", "varies", "until golden", "P", "PT0S", "0 minutes", "0:00", "1:75"} {
+		if got, err := ParseDuration(text); err == nil {
+			t.Errorf("ParseDuration(%q) = %+v, want an error", text, got)
+		}
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   int
+		wantOK bool
+	}{
+		{"PT1H30M", 90, true},
+		{"PT0.5H", 30, true},
+		{"P1W", 7 * 24 * 60, true},
+		{"1 hour", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseISODuration(test.text)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("ParseISODuration(%q) = %d, %t, want %d, %t", test.text, got, ok, test.want, test.wantOK)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package recipe
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EquipmentOven is the equipment name of an oven, for filtering recipes that need none.
+const EquipmentOven = "oven"
+
+// equipmentTerm is an entry of the equipment lexicon. Name is the name the equipment is
+// listed as, which may refer to the groups of the pattern, e.g. "${1}x${2}-inch pan". Terms
+// without a name are words that look like equipment but are not, e.g. "baking soda" or the
+// noun "roast" of "pot roast".
+type equipmentTerm struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// newEquipmentTerm compiles pattern into a case insensitive term that matches whole words.
+func newEquipmentTerm(name, pattern string) equipmentTerm {
+	return equipmentTerm{name: name, pattern: regexp.MustCompile(`(?i)\b(?:` + pattern + `)\b`)}
+}
+
+// equipmentLexicon is the equipment recognised in the steps of recipes. The text a term
+// matches is not matched by the terms after it, so specific terms come before general ones,
+// e.g. "dutch oven" before "oven" and "cast-iron skillet" before "skillet".
+var equipmentLexicon = []equipmentTerm{
+	newEquipmentTerm("", `baking (?:soda|powder|chocolate|spray|mix|spices?)|(?:oven|pan)[- ](?:roasted|dried|fried|ready)|pot roasts?|roast beef|(?:the|a|your|leftover|cooked|sliced) roasts?(?: (?:chicken|pork|lamb|turkey|duck))?`),
+	newEquipmentTerm("parchment paper", `parchment(?: paper)?|baking paper`),
+	newEquipmentTerm("dutch oven", `dutch ovens?`),
+	newEquipmentTerm("toaster oven", `toaster ovens?`),
+	newEquipmentTerm("microwave", `microwave(?: ovens?|s|d|-safe)?|microwaving`),
+	newEquipmentTerm("slow cooker", `slow cookers?|crock-?pots?`),
+	newEquipmentTerm("pressure cooker", `pressure cookers?|instant pots?|multicookers?`),
+	newEquipmentTerm("air fryer", `air[- ]?fryers?`),
+	newEquipmentTerm("deep fryer", `deep[- ]?fryers?`),
+	newEquipmentTerm("stand mixer", `stand mixers?|(?:paddle|whisk|dough hook) attachment`),
+	newEquipmentTerm("hand mixer", `(?:hand|electric) mixers?`),
+	newEquipmentTerm("food processor", `food processors?`),
+	newEquipmentTerm("immersion blender", `(?:immersion|stick|hand) blenders?`),
+	newEquipmentTerm("blender", `blenders?`),
+	newEquipmentTerm("spice grinder", `(?:spice|coffee) grinders?`),
+	newEquipmentTerm("mortar and pestle", `mortar and pestle`),
+	newEquipmentTerm("ice cream maker", `ice cream (?:makers?|machines?)`),
+	newEquipmentTerm("waffle iron", `waffle (?:irons?|makers?)`),
+	newEquipmentTerm("cast-iron skillet", `cast[- ]iron (?:skillets?|pans?|frying pans?|griddles?)`),
+	newEquipmentTerm("grill pan", `grill pans?`),
+	newEquipmentTerm("${1}x${2}-inch pan", `(\d+)\s*(?:x|by|×)\s*(\d+)(?:\s*-?\s*inch(?:es)?|\s*in\.|")?(?:\s+(?:metal|glass|ceramic|square|rectangular))*\s+(?:baking\s+)?(?:pans?|dish(?:es)?)`),
+	newEquipmentTerm("springform pan", `springform(?: pans?)?`),
+	newEquipmentTerm("bundt pan", `bundt pans?`),
+	newEquipmentTerm("loaf pan", `loaf (?:pans?|tins?)`),
+	newEquipmentTerm("muffin tin", `(?:muffin|cupcake) (?:tins?|pans?)`),
+	newEquipmentTerm("tart pan", `tart (?:pans?|tins?)`),
+	newEquipmentTerm("pie dish", `pie (?:dish(?:es)?|plates?|pans?|tins?)`),
+	newEquipmentTerm("cake pan", `cake (?:pans?|tins?)`),
+	newEquipmentTerm("baking sheet", `(?:rimmed )?(?:baking|cookie) sheets?|sheet pans?|baking trays?`),
+	newEquipmentTerm("roasting pan", `roasting (?:pans?|tins?)`),
+	newEquipmentTerm("baking dish", `(?:baking|casserole|gratin) dish(?:es)?`),
+	newEquipmentTerm("ramekins", `ramekins?`),
+	newEquipmentTerm("wire rack", `(?:wire|cooling) racks?`),
+	newEquipmentTerm("skillet", `skillets?|frying pans?|fry pans?|saut[eé] pans?`),
+	newEquipmentTerm("wok", `woks?`),
+	newEquipmentTerm("griddle", `griddles?`),
+	newEquipmentTerm("saucepan", `sauce ?pans?`),
+	newEquipmentTerm("stockpot", `stock ?pots?`),
+	newEquipmentTerm("double boiler", `double boilers?|bain[- ]marie`),
+	newEquipmentTerm("steamer basket", `steamer baskets?|steamers?`),
+	newEquipmentTerm("grill", `grills?|grilling|barbecues?`),
+	newEquipmentTerm(EquipmentOven, `ovens?|bak(?:e|es|ing)|broil(?:s|ing|er|ers)?|roast(?:s|ing)?`),
+	newEquipmentTerm("colander", `colanders?`),
+	newEquipmentTerm("sieve", `(?:fine[- ]mesh )?(?:sieves?|strainers?)`),
+	newEquipmentTerm("thermometer", `(?:candy|deep[- ]fry|instant[- ]read|meat|oven) thermometers?|thermometers?`),
+	newEquipmentTerm("rolling pin", `rolling pins?`),
+	newEquipmentTerm("pastry brush", `pastry brush(?:es)?`),
+	newEquipmentTerm("piping bag", `(?:piping|pastry) bags?`),
+	newEquipmentTerm("mandoline", `mandolines?`),
+	newEquipmentTerm("zester", `zesters?|microplanes?`),
+	newEquipmentTerm("whisk", `whisks?`),
+	newEquipmentTerm("kitchen twine", `(?:kitchen |butcher'?s )?twine`),
+}
+
+// ExtractEquipment returns the equipment the steps of a recipe use, e.g. "stand mixer",
+// "9x13-inch pan" or "oven", in the order it is first used.
+func ExtractEquipment(steps []string) []string {
+	equipment := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, step := range steps {
+		for _, name := range stepEquipment(step) {
+			if !seen[name] {
+				seen[name] = true
+				equipment = append(equipment, name)
+			}
+		}
+	}
+	return equipment
+}
+
+// stepEquipment returns the names of the equipment in a step, in order.
+func stepEquipment(step string) []string {
+	type match struct {
+		name  string
+		start int
+	}
+	matches := make([]match, 0)
+	text := []byte(step)
+	for _, term := range equipmentLexicon {
+		for _, indices := range term.pattern.FindAllSubmatchIndex(text, -1) {
+			if term.name != "" {
+				name := string(term.pattern.Expand(nil, []byte(term.name), text, indices))
+				matches = append(matches, match{name: strings.ToLower(name), start: indices[0]})
+			}
+			// Blank out the match, so that later terms do not match it again.
+			for i := indices[0]; i < indices[1]; i++ {
+				text[i] = ' '
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// NormalizeEquipment trims the equipment a page lists, and leaves out empty and repeated
+// items. Items are kept as the page writes them, e.g. "9-inch pie plate".
+func NormalizeEquipment(items []string) []string {
+	equipment := make([]string, 0, len(items))
+	seen := make(map[string]bool)
+	for _, item := range items {
+		item = strings.Join(strings.Fields(item), " ")
+		if item == "" || seen[strings.ToLower(item)] {
+			continue
+		}
+		seen[strings.ToLower(item)] = true
+		equipment = append(equipment, item)
+	}
+	return equipment
+}
+
+// HasEquipment returns whether the recipe uses equipment of a name of the lexicon, e.g.
+// EquipmentOven. Equipment listed by the page is matched against the lexicon as well, so a
+// listed "Large Dutch oven" is a dutch oven but not an oven.
+func (r *Recipe) HasEquipment(name string) bool {
+	for _, item := range r.Equipment {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+		for _, found := range stepEquipment(item) {
+			if found == name {
+				return true
+			}
+		}
+	}
+	return false
+}
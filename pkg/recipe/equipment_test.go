@@ -0,0 +1,55 @@
+package recipe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractEquipment(t *testing.T) {
+	tests := []struct {
+		steps []string
+		want  []string
+	}{
+		{
+			[]string{"Preheat the oven to 350°F. Grease a 9x13-inch baking dish.", "Bake for 30 minutes."},
+			[]string{"oven", "9x13-inch pan"},
+		},
+		{
+			[]string{"Heat oil in a large Dutch oven over medium heat."},
+			[]string{"dutch oven"},
+		},
+		{
+			[]string{"Beat the butter in a stand mixer fitted with the paddle attachment, then add the baking soda."},
+			[]string{"stand mixer"},
+		},
+		{
+			[]string{"Melt the butter in a cast-iron skillet.", "Transfer to a skillet and broil until golden."},
+			[]string{"cast-iron skillet", "skillet", "oven"},
+		},
+		{
+			[]string{"Line a baking sheet with parchment paper and roast the vegetables for 25 minutes."},
+			[]string{"baking sheet", "parchment paper", "oven"},
+		},
+		{
+			[]string{"Place the pot roast in a slow cooker and cook on low for 8 hours."},
+			[]string{"slow cooker"},
+		},
+		{
+			[]string{"Grill the roast beef sandwiches."},
+			[]string{"grill"},
+		},
+		{
+			[]string{"Let the roast rest on a cutting board, then slice."},
+			[]string{},
+		},
+		{
+			[]string{"Stir in the oven-roasted tomatoes and serve."},
+			[]string{},
+		},
+	}
+	for _, test := range tests {
+		if got := ExtractEquipment(test.steps); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ExtractEquipment(%q) = %q, want %q", test.steps, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package recipe
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Image is a picture of a recipe, or of one of its steps.
+type Image struct {
+	URL string `yaml:"url"`
+	// Width and Height are the size of the image in pixels, 0 if the page does not give it.
+	Width  int    `yaml:"width,omitempty"`
+	Height int    `yaml:"height,omitempty"`
+	Alt    string `yaml:"alt,omitempty"`
+	// Step is the number of the step the image shows, from 1, or 0 for the whole recipe.
+	Step int `yaml:"step,omitempty"`
+}
+
+// Video is a video of a recipe.
+type Video struct {
+	URL          string `yaml:"url,omitempty"`
+	ThumbnailURL string `yaml:"thumbnail_url,omitempty"`
+	// Seconds is the length of the video, 0 if the page does not give it.
+	Seconds int `yaml:"seconds,omitempty"`
+}
+
+// AddImages adds images to the images of the recipe, leaving out images without a URL and
+// images it already has. The first image of the whole recipe is also kept as its image.
+func (m *RecipeMetadata) AddImages(images ...Image) {
+	for _, image := range images {
+		image.URL = strings.TrimSpace(image.URL)
+		if image.URL == "" || m.hasImage(image.URL) {
+			continue
+		}
+		m.Images = append(m.Images, image)
+		if m.ImageURL == "" && image.Step == 0 {
+			m.ImageURL, m.ImageAlt = image.URL, image.Alt
+		}
+	}
+}
+
+// hasImage returns whether the recipe has an image with the URL.
+func (m *RecipeMetadata) hasImage(url string) bool {
+	for _, image := range m.Images {
+		if image.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveURLs resolves the relative URLs of the images and video of the recipe against
+// base, the URL of the page the recipe is on. A nil base leaves them as they are.
+func (m *RecipeMetadata) ResolveURLs(base *url.URL) {
+	if base == nil {
+		return
+	}
+	m.ImageURL = ResolveURL(base, m.ImageURL)
+	for i := range m.Images {
+		m.Images[i].URL = ResolveURL(base, m.Images[i].URL)
+	}
+	if m.Video != nil {
+		m.Video.URL = ResolveURL(base, m.Video.URL)
+		m.Video.ThumbnailURL = ResolveURL(base, m.Video.ThumbnailURL)
+	}
+}
+
+// ResolveURL resolves a URL, e.g. "//example.com/a.jpg" or "/a.jpg", against base. Empty and
+// invalid URLs are returned as they are.
+func ResolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil || ref == "" {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
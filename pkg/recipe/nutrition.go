@@ -0,0 +1,202 @@
+package recipe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Nutrient is a nutrition fact of a recipe.
+type Nutrient string
+
+const (
+	NutrientCalories     Nutrient = "calories"
+	NutrientFat          Nutrient = "fat"
+	NutrientSaturatedFat Nutrient = "saturated_fat"
+	NutrientCarbohydrate Nutrient = "carbohydrate"
+	NutrientFiber        Nutrient = "fiber"
+	NutrientSugar        Nutrient = "sugar"
+	NutrientProtein      Nutrient = "protein"
+	NutrientSodium       Nutrient = "sodium"
+	NutrientCholesterol  Nutrient = "cholesterol"
+)
+
+// nutrientNames maps the names nutrients are labelled with, in lower case without spaces, to
+// the nutrients. schema.org names like fatContent are matched without "content".
+var nutrientNames = map[string]Nutrient{
+	"calories":           NutrientCalories,
+	"calorie":            NutrientCalories,
+	"energy":             NutrientCalories,
+	"fat":                NutrientFat,
+	"totalfat":           NutrientFat,
+	"saturatedfat":       NutrientSaturatedFat,
+	"satfat":             NutrientSaturatedFat,
+	"carbohydrate":       NutrientCarbohydrate,
+	"carbohydrates":      NutrientCarbohydrate,
+	"totalcarbohydrate":  NutrientCarbohydrate,
+	"totalcarbohydrates": NutrientCarbohydrate,
+	"carbs":              NutrientCarbohydrate,
+	"fiber":              NutrientFiber,
+	"fibre":              NutrientFiber,
+	"dietaryfiber":       NutrientFiber,
+	"sugar":              NutrientSugar,
+	"sugars":             NutrientSugar,
+	"totalsugars":        NutrientSugar,
+	"protein":            NutrientProtein,
+	"sodium":             NutrientSodium,
+	"cholesterol":        NutrientCholesterol,
+}
+
+// REs for the amounts of nutrients.
+var (
+	// nutrientAmountRE matches an amount of a nutrient and its unit, e.g. "7 g", "320kcal",
+	// "1,250 kcal" or "2,5 g".
+	nutrientAmountRE = regexp.MustCompile(`(?i)(\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:[.,]\d+)?)\s*(kcal|cal(?:ories)?|kj|mg|milligrams?|mcg|µg|micrograms?|g|grams?)?\b`)
+	// nutrientThousandsRE matches amounts with thousands separators, e.g. "1,017". A comma
+	// followed by other than three digits is a decimal comma.
+	nutrientThousandsRE = regexp.MustCompile(`^\d{1,3}(?:,\d{3})+(?:\.\d+)?$`)
+)
+
+// Nutrition is the nutrition facts of a recipe. Calories are in kcal, sodium and cholesterol
+// in milligrams and the other nutrients in grams. Nutrients the recipe does not give are -1.
+type Nutrition struct {
+	// ServingSize is the amount the facts are given for, e.g. "1 slice". If empty, they are
+	// given for a serving of the yield of the recipe.
+	ServingSize string `yaml:"serving_size,omitempty"`
+
+	Calories              float64 `yaml:"calories"`
+	FatGrams              float64 `yaml:"fat_grams"`
+	SaturatedFatGrams     float64 `yaml:"saturated_fat_grams"`
+	CarbohydrateGrams     float64 `yaml:"carbohydrate_grams"`
+	FiberGrams            float64 `yaml:"fiber_grams"`
+	SugarGrams            float64 `yaml:"sugar_grams"`
+	ProteinGrams          float64 `yaml:"protein_grams"`
+	SodiumMilligrams      float64 `yaml:"sodium_milligrams"`
+	CholesterolMilligrams float64 `yaml:"cholesterol_milligrams"`
+}
+
+// NewNutrition returns nutrition facts without any nutrients.
+func NewNutrition() *Nutrition {
+	return &Nutrition{
+		Calories:              -1,
+		FatGrams:              -1,
+		SaturatedFatGrams:     -1,
+		CarbohydrateGrams:     -1,
+		FiberGrams:            -1,
+		SugarGrams:            -1,
+		ProteinGrams:          -1,
+		SodiumMilligrams:      -1,
+		CholesterolMilligrams: -1,
+	}
+}
+
+// ParseNutrient returns the nutrient of a label like "Total Fat", "Dietary Fiber" or the
+// schema.org property fatContent. ok is false if the label is not a nutrient.
+func ParseNutrient(label string) (nutrient Nutrient, ok bool) {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(label))
+	nutrient, ok = nutrientNames[strings.TrimSuffix(name, "content")]
+	return nutrient, ok
+}
+
+// Set parses an amount like "7 g", "420 mg" or "320 kcal" and sets it as the amount of
+// nutrient. Amounts in other units of mass or energy are converted. An amount without a
+// unit is taken to be in the unit of the nutrient.
+func (n *Nutrition) Set(nutrient Nutrient, text string) error {
+	amount := n.amount(nutrient)
+	if amount == nil {
+		return fmt.Errorf("unknown nutrient %q", nutrient)
+	}
+	matches := nutrientAmountRE.FindStringSubmatch(text)
+	if matches == nil {
+		return fmt.Errorf("no amount of %s in %q", nutrient, text)
+	}
+	number := strings.ReplaceAll(matches[1], ",", ".")
+	if nutrientThousandsRE.MatchString(matches[1]) {
+		number = strings.ReplaceAll(matches[1], ",", "")
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return fmt.Errorf("no amount of %s in %q", nutrient, text)
+	}
+
+	unit := strings.ToLower(matches[2])
+	switch {
+	case unit == "":
+	case nutrient == NutrientCalories:
+		if unit == "kj" {
+			value /= 4.184
+		} else if unit != "kcal" && !strings.HasPrefix(unit, "cal") {
+			return fmt.Errorf("%q is not an amount of energy", text)
+		}
+	case unit == "kj" || unit == "kcal" || strings.HasPrefix(unit, "cal"):
+		return fmt.Errorf("%q is not an amount of %s", text, nutrient)
+	default:
+		value *= nutrientGrams(unit) / nutrientGrams(n.unit(nutrient))
+	}
+	*amount = value
+	return nil
+}
+
+// Empty returns whether none of the nutrients are known.
+func (n *Nutrition) Empty() bool {
+	for _, nutrient := range []Nutrient{
+		NutrientCalories, NutrientFat, NutrientSaturatedFat, NutrientCarbohydrate, NutrientFiber,
+		NutrientSugar, NutrientProtein, NutrientSodium, NutrientCholesterol,
+	} {
+		if *n.amount(nutrient) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// amount returns the field of nutrient, or nil if it is not a nutrient.
+func (n *Nutrition) amount(nutrient Nutrient) *float64 {
+	switch nutrient {
+	case NutrientCalories:
+		return &n.Calories
+	case NutrientFat:
+		return &n.FatGrams
+	case NutrientSaturatedFat:
+		return &n.SaturatedFatGrams
+	case NutrientCarbohydrate:
+		return &n.CarbohydrateGrams
+	case NutrientFiber:
+		return &n.FiberGrams
+	case NutrientSugar:
+		return &n.SugarGrams
+	case NutrientProtein:
+		return &n.ProteinGrams
+	case NutrientSodium:
+		return &n.SodiumMilligrams
+	case NutrientCholesterol:
+		return &n.CholesterolMilligrams
+	}
+	return nil
+}
+
+// unit returns the unit of mass nutrient is kept in.
+func (n *Nutrition) unit(nutrient Nutrient) string {
+	if nutrient == NutrientSodium || nutrient == NutrientCholesterol {
+		return "mg"
+	}
+	return "g"
+}
+
+// nutrientGrams returns the grams of a unit of mass matched by nutrientAmountRE.
+func nutrientGrams(unit string) float64 {
+	switch {
+	case unit == "mg" || strings.HasPrefix(unit, "milli"):
+		return 1e-3
+	case unit == "mcg" || unit == "µg" || strings.HasPrefix(unit, "micro"):
+		return 1e-6
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,50 @@
+package recipe
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNutritionSet(t *testing.T) {
+	tests := []struct {
+		nutrient Nutrient
+		text     string
+		want     float64
+		wantErr  bool
+	}{
+		{NutrientFat, "7 g", 7, false},
+		{NutrientSodium, "420 mg", 420, false},
+		{NutrientSodium, "1,017 mg", 1017, false},
+		{NutrientCalories, "1,250 kcal", 1250, false},
+		{NutrientCalories, "1,250.5 kcal", 1250.5, false},
+		{NutrientFat, "2,5 g", 2.5, false},
+		{NutrientFat, "12,75g", 12.75, false},
+		{NutrientCalories, "320kcal", 320, false},
+		{NutrientCalories, "320 Calories", 320, false},
+		{NutrientCalories, "1000 kJ", 239.006, false},
+		{NutrientSodium, "1.2 g", 1200, false},
+		{NutrientProtein, "500 mg", 0.5, false},
+		{NutrientProtein, "12", 12, false},
+		{NutrientFat, "320 kcal", 0, true},
+		{NutrientCalories, "7 g", 0, true},
+		{NutrientFat, "none", 0, true},
+		{Nutrient("vitamin_c"), "7 mg", 0, true},
+	}
+	for _, test := range tests {
+		nutrition := NewNutrition()
+		err := nutrition.Set(test.nutrient, test.text)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("Set(%s, %q) = nil error, want an error", test.nutrient, test.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%s, %q) = %v", test.nutrient, test.text, err)
+			continue
+		}
+		if got := *nutrition.amount(test.nutrient); math.Abs(got-test.want) > 0.001 {
+			t.Errorf("Set(%s, %q) set %v, want %v", test.nutrient, test.text, got, test.want)
+		}
+	}
+}
@@ -5,16 +5,92 @@ type RawRecipe struct {
 	Description            string
 	IngredientDescriptions []string
 	Steps                  []string
+	// IngredientGroups split IngredientDescriptions, in order, into the named groups of
+	// the recipe, e.g. "For the crust" and "For the filling". It is empty if the recipe
+	// does not group its ingredients.
+	IngredientGroups []IngredientGroup `yaml:",omitempty"`
+	// StepSections split Steps, in order, into the named sections of the recipe.
+	StepSections []StepSection `yaml:",omitempty"`
+	// Equipment is the equipment the page lists for the recipe, empty if it lists none.
+	Equipment []string `yaml:",omitempty"`
 
 	Metadata RecipeMetadata
 }
 
+// IngredientGroup is a named group of the ingredients of a raw recipe. Ingredients that
+// come before the first named group are in a group without a name.
+type IngredientGroup struct {
+	Name        string   `yaml:"name"`
+	Ingredients []string `yaml:"ingredients"`
+}
+
+// StepSection is a named section of the steps of a recipe, e.g. "Make the crust". Steps
+// that come before the first named section are in a section without a name.
+type StepSection struct {
+	Name  string   `yaml:"name"`
+	Steps []string `yaml:"steps"`
+}
+
+// ToRecipe returns the recipe without its ingredients, which are parsed by the processor.
+// Equipment the page does not list is extracted from the steps.
 func (r *RawRecipe) ToRecipe() *Recipe {
+	equipment := r.Equipment
+	if len(equipment) == 0 {
+		equipment = ExtractEquipment(r.Steps)
+	}
 	return &Recipe{
-		Name:        r.Name,
-		Description: r.Description,
-		Ingredients: nil,
-		Steps:       r.Steps,
-		Metadata:    r.Metadata,
+		Name:         r.Name,
+		Description:  r.Description,
+		Ingredients:  nil,
+		Steps:        r.Steps,
+		StepSections: r.StepSections,
+		Equipment:    equipment,
+		Metadata:     r.Metadata,
+	}
+}
+
+// SetIngredientGroups sets the ingredient groups of the recipe, and its ingredient
+// descriptions to the ingredients of every group in order. If there is only a group
+// without a name, the recipe is left ungrouped.
+func (r *RawRecipe) SetIngredientGroups(groups []IngredientGroup) {
+	r.IngredientDescriptions = make([]string, 0)
+	for _, group := range groups {
+		r.IngredientDescriptions = append(r.IngredientDescriptions, group.Ingredients...)
+	}
+	r.IngredientGroups = nil
+	if len(groups) > 1 || (len(groups) == 1 && groups[0].Name != "") {
+		r.IngredientGroups = groups
+	}
+}
+
+// SetStepSections sets the step sections of the recipe, and its steps to the steps of
+// every section in order. If there is only a section without a name, the recipe is left
+// without sections.
+func (r *RawRecipe) SetStepSections(sections []StepSection) {
+	r.Steps = make([]string, 0)
+	for _, section := range sections {
+		r.Steps = append(r.Steps, section.Steps...)
+	}
+	r.StepSections = nil
+	if len(sections) > 1 || (len(sections) == 1 && sections[0].Name != "") {
+		r.StepSections = sections
+	}
+}
+
+// IngredientGroupNames returns the name of the group of each ingredient description, or
+// nil if the recipe has no groups or they do not match its ingredient descriptions.
+func (r *RawRecipe) IngredientGroupNames() []string {
+	if len(r.IngredientGroups) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(r.IngredientDescriptions))
+	for _, group := range r.IngredientGroups {
+		for range group.Ingredients {
+			names = append(names, group.Name)
+		}
+	}
+	if len(names) != len(r.IngredientDescriptions) {
+		return nil
 	}
+	return names
 }
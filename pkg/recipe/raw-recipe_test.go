@@ -0,0 +1,85 @@
+package recipe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetIngredientGroups(t *testing.T) {
+	tests := []struct {
+		groups          []IngredientGroup
+		wantIngredients []string
+		wantGroups      bool
+		wantNames       []string
+	}{
+		{
+			groups: []IngredientGroup{
+				{Ingredients: []string{"1 egg"}},
+				{Name: "For the crust", Ingredients: []string{"1 cup flour", "1/2 cup butter"}},
+			},
+			wantIngredients: []string{"1 egg", "1 cup flour", "1/2 cup butter"},
+			wantGroups:      true,
+			wantNames:       []string{"", "For the crust", "For the crust"},
+		},
+		{
+			groups:          []IngredientGroup{{Name: "Filling", Ingredients: []string{"2 apples"}}},
+			wantIngredients: []string{"2 apples"},
+			wantGroups:      true,
+			wantNames:       []string{"Filling"},
+		},
+		// A single group without a name leaves the recipe ungrouped.
+		{
+			groups:          []IngredientGroup{{Ingredients: []string{"1 egg", "salt"}}},
+			wantIngredients: []string{"1 egg", "salt"},
+		},
+		{
+			groups:          nil,
+			wantIngredients: []string{},
+		},
+	}
+	for _, test := range tests {
+		r := &RawRecipe{IngredientDescriptions: []string{"stale"}}
+		r.SetIngredientGroups(test.groups)
+		if !reflect.DeepEqual(r.IngredientDescriptions, test.wantIngredients) {
+			t.Errorf("SetIngredientGroups(%v) ingredients = %q, want %q", test.groups, r.IngredientDescriptions, test.wantIngredients)
+		}
+		if (r.IngredientGroups != nil) != test.wantGroups {
+			t.Errorf("SetIngredientGroups(%v) groups = %v, want grouped %t", test.groups, r.IngredientGroups, test.wantGroups)
+		}
+		if got := r.IngredientGroupNames(); !reflect.DeepEqual(got, test.wantNames) {
+			t.Errorf("SetIngredientGroups(%v) IngredientGroupNames() = %q, want %q", test.groups, got, test.wantNames)
+		}
+	}
+}
+
+func TestIngredientGroupNamesMismatch(t *testing.T) {
+	r := &RawRecipe{
+		IngredientDescriptions: []string{"1 egg"},
+		IngredientGroups:       []IngredientGroup{{Name: "Batter", Ingredients: []string{"1 egg", "1 cup milk"}}},
+	}
+	if got := r.IngredientGroupNames(); got != nil {
+		t.Errorf("IngredientGroupNames() of groups that do not match the ingredients = %q, want nil", got)
+	}
+}
+
+func TestSetStepSections(t *testing.T) {
+	r := &RawRecipe{}
+	r.SetStepSections([]StepSection{
+		{Name: "Make the crust", Steps: []string{"Mix the flour and butter.", "Chill."}},
+		{Name: "Bake", Steps: []string{"Bake for 30 minutes."}},
+	})
+	if want := []string{"Mix the flour and butter.", "Chill.", "Bake for 30 minutes."}; !reflect.DeepEqual(r.Steps, want) {
+		t.Errorf("SetStepSections() steps = %q, want %q", r.Steps, want)
+	}
+	if len(r.StepSections) != 2 {
+		t.Errorf("SetStepSections() sections = %v, want 2 sections", r.StepSections)
+	}
+
+	r.SetStepSections([]StepSection{{Steps: []string{"Stir."}}})
+	if !reflect.DeepEqual(r.Steps, []string{"Stir."}) || r.StepSections != nil {
+		t.Errorf("SetStepSections() of a section without a name = %q, %v, want the steps without sections", r.Steps, r.StepSections)
+	}
+	if got := r.ToRecipe().StepSections; got != nil {
+		t.Errorf("ToRecipe() step sections = %v, want nil", got)
+	}
+}
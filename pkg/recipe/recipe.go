@@ -1,6 +1,9 @@
 package recipe
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type RecipeDifficulty int
 
@@ -15,6 +18,9 @@ type IngredientItem struct {
 	Amount   Amount
 	Optional bool
 	Notes    string
+	// Group is the name of the ingredient group of the recipe the ingredient is in, e.g.
+	// "For the crust", or empty if the recipe does not group its ingredients.
+	Group string `yaml:",omitempty"`
 }
 
 type IngredientList []IngredientItem
@@ -24,28 +30,75 @@ type Recipe struct {
 	Description string         `yaml:"description"`
 	Ingredients IngredientList `yaml:"ingredients"`
 	Steps       []string       `yaml:"steps"`
+	// StepSections split Steps, in order, into the named sections of the recipe.
+	StepSections []StepSection `yaml:"step_sections,omitempty"`
+	// Equipment is the equipment the recipe needs, e.g. "stand mixer" or "oven". It is
+	// listed by the page, or else extracted from Steps.
+	Equipment []string `yaml:"equipment"`
+	// StepAnnotations are the timers, temperatures, heat levels and ingredients of each of
+	// Steps, in order.
+	StepAnnotations []StepAnnotation `yaml:"step_annotations,omitempty"`
 
 	Metadata RecipeMetadata `yaml:"metadata"`
 }
 
 type RecipeMetadata struct {
-	Tags              []string         `yaml:"tags"`
-	MinutesToPrep     int              `yaml:"minutes_to_prep"`
-	MinutesToCook     int              `yaml:"minutes_to_cook"`
-	MinutesTotal      int              `yaml:"minutes_total"`
+	Tags          []string `yaml:"tags"`
+	MinutesToPrep int      `yaml:"minutes_to_prep"`
+	MinutesToCook int      `yaml:"minutes_to_cook"`
+	MinutesTotal  int      `yaml:"minutes_total"`
+	// MinutesInactive is the time the dish is left alone for, e.g. to chill or rise, which
+	// is part of MinutesTotal but not of the prep and cook times.
+	MinutesInactive   int              `yaml:"minutes_inactive"`
 	Difficulty        RecipeDifficulty `yaml:"difficulty"`
 	Servings          ServingRange     `yaml:"servings"`
 	EstimatedCalories int              `yaml:"estimated_calories"`
-	ImageURL          string           `yaml:"image_url"`
-	ImageAlt          string           `yaml:"image_alt"`
-	SourceURL         string           `yaml:"source_url"`
+	// Nutrition is the nutrition facts of the recipe, nil if it has none.
+	Nutrition *Nutrition `yaml:"nutrition,omitempty"`
+	// ImageURL and ImageAlt are the main image of the recipe, the first of Images that is
+	// not of a step.
+	ImageURL string  `yaml:"image_url"`
+	ImageAlt string  `yaml:"image_alt"`
+	Images   []Image `yaml:"images,omitempty"`
+	// Video is the video of the recipe, nil if it has none.
+	Video     *Video `yaml:"video,omitempty"`
+	SourceURL string `yaml:"source_url"`
+
+	// Author is the chef or author the recipe is attributed to.
+	Author string `yaml:"author,omitempty"`
+	// Rating is the average rating of the recipe out of 5, 0 if it has not been rated.
+	Rating      float64 `yaml:"rating"`
+	ReviewCount int     `yaml:"review_count"`
+	// PublishedAt and UpdatedAt are when the recipe was published and last updated on its
+	// site, zero if the site does not show them.
+	PublishedAt time.Time `yaml:"published_at,omitempty"`
+	UpdatedAt   time.Time `yaml:"updated_at,omitempty"`
 
 	Dietary RecipeDietaryInformation `yaml:"dietary"`
 }
 
+// LastUpdated returns when the recipe was last updated, or published if it was never
+// updated.
+func (m RecipeMetadata) LastUpdated() time.Time {
+	if m.UpdatedAt.After(m.PublishedAt) {
+		return m.UpdatedAt
+	}
+	return m.PublishedAt
+}
+
+// ServingRange is the yield of a recipe, e.g. 4 to 6 servings or 2 loaves.
 type ServingRange struct {
-	Min         int    `yaml:"min"`
-	Max         int    `yaml:"max"`
+	// Min and Max are the whole number of the unit, rounded to the nearest but at least 1.
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+	// MinQuantity and MaxQuantity are the exact quantities, e.g. 0.75 of "3/4 cup".
+	MinQuantity float64 `yaml:"min_quantity,omitempty"`
+	MaxQuantity float64 `yaml:"max_quantity,omitempty"`
+	// Unit is what the quantities count.
+	Unit YieldUnit `yaml:"unit,omitempty"`
+	// Item is what is made, e.g. "cookies" or "pie", if the yield is in pieces or whole dishes.
+	Item string `yaml:"item,omitempty"`
+	// Alternative is the text of a yield that could not be parsed.
 	Alternative string `yaml:"alternative"`
 }
 
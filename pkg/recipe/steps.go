@@ -0,0 +1,330 @@
+package recipe
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HeatLevel is the heat of a burner a step cooks over.
+type HeatLevel string
+
+const (
+	HeatLow        HeatLevel = "low"
+	HeatMediumLow  HeatLevel = "medium-low"
+	HeatMedium     HeatLevel = "medium"
+	HeatMediumHigh HeatLevel = "medium-high"
+	HeatHigh       HeatLevel = "high"
+)
+
+// TemperatureUnit is the scale a temperature is written in.
+type TemperatureUnit string
+
+const (
+	Fahrenheit TemperatureUnit = "F"
+	Celsius    TemperatureUnit = "C"
+)
+
+// REs for the timers, temperatures and heat levels of steps.
+var (
+	// stepDurationJoinRE matches the text between the parts of a duration, e.g. the " and "
+	// of "1 hour and 15 minutes".
+	stepDurationJoinRE = regexp.MustCompile(`(?i)^(?:\s*,?\s*(?:and|plus)?\s*)$`)
+	// stepDurationRE matches the durations of steps like durationRE, and the word before
+	// them that says they are a time, e.g. the "for" of "for a minute". Bare m and s are
+	// not units in steps, as in "5 m." they are more likely to be meters or a typo.
+	stepDurationRE = regexp.MustCompile(`(?i)(?:\b(for|about|around|approximately|until|another)\s+)?(` + durationNumber + `)(?:\s*(?:-|–|—|\bto\b|\bor\b)\s*(` + durationNumber + `))?\s*-?\s*(?:(?:more|additional|extra|further)\s+)?(days?|d|hours?|hrs?|h|minutes?|mins?|seconds?|secs?)\b`)
+	// temperatureRE matches temperatures with a degree sign or word, e.g. "350°F",
+	// "180 degrees C" or "350 degrees", and ones of three digits with only a scale, e.g.
+	// "350F" or "180 C". Shorter ones are not, as "2 c" is more likely to be cups.
+	temperatureRE = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(?:°|º|˚|\bdegrees?\b|\bdeg\b\.?)(?:\s*(fahrenheit\b|celsius\b|centigrade\b|f\b|c\b))?|\b(\d{3})\s?(f|c)\b`)
+	// rotationRE matches the turns of a pan or dish, whose degrees are an angle, e.g. "turn
+	// the pan 180 degrees".
+	rotationRE = regexp.MustCompile(`(?i)\b(?:turn|rotate|flip|spin|swivel)(?:s|ed|ing)?\b`)
+	// heatWordRE matches the words that make a bare "350 degrees" a temperature.
+	heatWordRE = regexp.MustCompile(`(?i)\b(?:oven|preheat|heat|bake|fry|fries|fried|oil|water|roast|broil|grill|temperature)(?:s|ed|ing)?\b`)
+	// sentenceEndRE matches the ends of the sentences of a step.
+	sentenceEndRE = regexp.MustCompile(`[.;!?](?:\s|$)`)
+	// heatLevelRE matches the heat a step cooks over, e.g. "over medium-high heat".
+	heatLevelRE = regexp.MustCompile(`(?i)\b(low|medium[- ]low|moderately low|medium|moderate|medium[- ]high|moderately high|high)\s+(?:heat|flame)\b`)
+	// ingredientWordRE matches the words of the names of ingredients and of steps.
+	ingredientWordRE = regexp.MustCompile(`[\p{L}\d']+`)
+)
+
+// StepAnnotation is a step of a recipe with what a cooking mode needs to follow it: the
+// timers to start, the temperatures and heat to cook at, and the ingredients it uses.
+type StepAnnotation struct {
+	Text         string        `yaml:"text"`
+	Timers       []Timer       `yaml:"timers,omitempty"`
+	Temperatures []Temperature `yaml:"temperatures,omitempty"`
+	HeatLevels   []HeatLevel   `yaml:"heat_levels,omitempty"`
+	// Ingredients are the indexes in the ingredients of the recipe of the ingredients the
+	// step uses.
+	Ingredients []int `yaml:"ingredients,omitempty"`
+}
+
+// Timer is a duration in a step, e.g. "25 to 30 minutes".
+type Timer struct {
+	// Text is the duration as the step writes it.
+	Text string `yaml:"text"`
+	// MinSeconds and MaxSeconds are the shortest and longest time of a range, both the
+	// time of durations that are not ranges.
+	MinSeconds int `yaml:"min_seconds"`
+	MaxSeconds int `yaml:"max_seconds"`
+}
+
+// Temperature is an oven, stovetop or oil temperature in a step, in both scales.
+type Temperature struct {
+	// Text is the temperature as the step writes it, e.g. "350°F (180°C)".
+	Text string `yaml:"text"`
+	// Unit is the scale the step gives the temperature in first.
+	Unit       TemperatureUnit `yaml:"unit"`
+	Fahrenheit int             `yaml:"fahrenheit"`
+	Celsius    int             `yaml:"celsius"`
+}
+
+// AnnotateSteps annotates every step of the recipe, with references to its ingredients.
+func (r *Recipe) AnnotateSteps() []StepAnnotation {
+	annotations := make([]StepAnnotation, len(r.Steps))
+	for i, step := range r.Steps {
+		annotations[i] = AnnotateStep(step, r.Ingredients)
+	}
+	return annotations
+}
+
+// AnnotateStep finds the timers, temperatures, heat levels and ingredients of a step.
+func AnnotateStep(step string, ingredients IngredientList) StepAnnotation {
+	return StepAnnotation{
+		Text:         step,
+		Timers:       stepTimers(step),
+		Temperatures: stepTemperatures(step),
+		HeatLevels:   stepHeatLevels(step),
+		Ingredients:  stepIngredients(step, ingredients),
+	}
+}
+
+// stepTimers returns the durations of a step. The parts of a duration like "1 hour and
+// 15 minutes" make a single timer. Durations without a number, like "a minute", are only
+// timers after a word like "for", and never of seconds, so that "a second time" is not.
+func stepTimers(step string) []Timer {
+	timers := make([]Timer, 0)
+	start, end := 0, -1
+	for _, matches := range stepDurationRE.FindAllStringSubmatchIndex(step, -1) {
+		// The duration starts at its number, after the word before it.
+		begin := matches[4]
+		number := strings.ToLower(step[matches[4]:matches[5]])
+		unit := strings.ToLower(step[matches[8]:matches[9]])
+		if strings.IndexAny(number, "0123456789½¼¾") != 0 {
+			if matches[2] < 0 || ((number == "a" || number == "an") && strings.HasPrefix(unit, "s")) {
+				continue
+			}
+		}
+		min, max, ok := sumDurations(step[begin:matches[1]])
+		if !ok {
+			continue
+		}
+		timer := Timer{MinSeconds: int(math.Round(min * 60)), MaxSeconds: int(math.Round(max * 60))}
+		if last := len(timers) - 1; last >= 0 && stepDurationJoinRE.MatchString(step[end:begin]) {
+			timers[last].Text = step[start:matches[1]]
+			timers[last].MinSeconds += timer.MinSeconds
+			timers[last].MaxSeconds += timer.MaxSeconds
+		} else {
+			start = begin
+			timer.Text = step[start:matches[1]]
+			timers = append(timers, timer)
+		}
+		end = matches[1]
+	}
+	return timers
+}
+
+// stepTemperatures returns the temperatures of a step. A temperature followed by its
+// conversion, like "350°F (180°C)", is a single temperature. Temperatures without a scale
+// are taken to be Fahrenheit if they are above the boiling point of water in Celsius.
+// Degrees of turning a pan are not temperatures, and degrees without a sign or scale are
+// only temperatures in a sentence about an oven, oil or other heat.
+func stepTemperatures(step string) []Temperature {
+	temperatures := make([]Temperature, 0)
+	start, end := 0, -1
+	for _, matches := range temperatureRE.FindAllStringSubmatchIndex(step, -1) {
+		// The value and scale are groups 1 and 2 with a degree sign, 3 and 4 without.
+		valueGroup, scaleGroup := 2, 4
+		if matches[2] < 0 {
+			valueGroup, scaleGroup = 6, 8
+		}
+		before, sentence := stepSentence(step, matches[0], matches[1])
+		if rotationRE.MatchString(before) {
+			continue
+		}
+		if matches[scaleGroup] < 0 && !strings.ContainsAny(step[matches[0]:matches[1]], "°º˚") && !heatWordRE.MatchString(sentence) {
+			continue
+		}
+		value, err := strconv.ParseFloat(step[matches[valueGroup]:matches[valueGroup+1]], 64)
+		if err != nil {
+			continue
+		}
+		scale := ""
+		if matches[scaleGroup] >= 0 {
+			scale = strings.ToLower(step[matches[scaleGroup]:matches[scaleGroup+1]])
+		}
+
+		temperature := Temperature{Text: step[matches[0]:matches[1]], Unit: Fahrenheit}
+		if strings.HasPrefix(scale, "c") || (scale == "" && value <= 100) {
+			temperature.Unit = Celsius
+		}
+		if temperature.Unit == Celsius {
+			temperature.Celsius = int(math.Round(value))
+			temperature.Fahrenheit = int(math.Round(value*9/5 + 32))
+		} else {
+			temperature.Fahrenheit = int(math.Round(value))
+			temperature.Celsius = int(math.Round((value - 32) * 5 / 9))
+		}
+
+		// A conversion of the temperature before it, e.g. the "(180°C)" of "350°F (180°C)".
+		if last := len(temperatures) - 1; last >= 0 && isTemperatureConversion(temperatures[last], temperature, step[end:matches[0]]) {
+			text := step[start:matches[1]]
+			if strings.Count(text, "(") > strings.Count(text, ")") && strings.HasPrefix(step[matches[1]:], ")") {
+				text += ")"
+			}
+			temperatures[last].Text = text
+			if temperatures[last].Unit == Fahrenheit {
+				temperatures[last].Celsius = temperature.Celsius
+			} else {
+				temperatures[last].Fahrenheit = temperature.Fahrenheit
+			}
+		} else {
+			start = matches[0]
+			temperatures = append(temperatures, temperature)
+		}
+		end = matches[1]
+	}
+	return temperatures
+}
+
+// stepSentence returns the text of the sentence of step that the text from start to end is
+// in, and the part of it before start.
+func stepSentence(step string, start, end int) (before, sentence string) {
+	sentenceStart, sentenceEnd := 0, len(step)
+	for _, indices := range sentenceEndRE.FindAllStringIndex(step, -1) {
+		if indices[1] <= start {
+			sentenceStart = indices[1]
+		} else if indices[0] >= end {
+			sentenceEnd = indices[0]
+			break
+		}
+	}
+	return step[sentenceStart:start], step[sentenceStart:sentenceEnd]
+}
+
+// isTemperatureConversion returns whether next is the conversion of previous to the other
+// scale, given the text between them. Sites round conversions, e.g. 350°F to 180°C rather
+// than 177°C.
+func isTemperatureConversion(previous, next Temperature, between string) bool {
+	between = strings.TrimSpace(between)
+	if previous.Unit == next.Unit || (between != "" && between != "(" && between != "/" && !strings.EqualFold(between, "or")) {
+		return false
+	}
+	return math.Abs(float64(previous.Celsius-next.Celsius)) <= 10
+}
+
+// stepHeatLevels returns the heat levels of a step in order, each level once.
+func stepHeatLevels(step string) []HeatLevel {
+	levels := make([]HeatLevel, 0)
+	seen := make(map[HeatLevel]bool)
+	for _, matches := range heatLevelRE.FindAllStringSubmatch(step, -1) {
+		text := strings.Replace(strings.ToLower(matches[1]), " ", "-", 1)
+		level := HeatLevel(strings.NewReplacer("moderately-", "medium-", "moderate", "medium").Replace(text))
+		if !seen[level] {
+			seen[level] = true
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// stepIngredients returns the indexes of the ingredients a step uses. An ingredient is used
+// if the step names it, or the end of its name, e.g. "flour" of "all-purpose flour", as
+// long as that does not name another ingredient as well, like "powder" of "baking powder"
+// and "garlic powder". Singular and plural names match each other.
+func stepIngredients(step string, ingredients IngredientList) []int {
+	stepWords := ingredientWords(step)
+	names := make([][]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		names[i] = ingredientWords(ingredient.Name)
+	}
+
+	used := make([]int, 0)
+	for i, name := range names {
+		for start := 0; start < len(name); start++ {
+			suffix := name[start:]
+			if !containsWords(stepWords, suffix) {
+				continue
+			}
+			if start == 0 || !sharedSuffix(names, i, suffix) {
+				used = append(used, i)
+			}
+			break
+		}
+	}
+	return used
+}
+
+// ingredientWords returns the words of text in lower case and in the singular, so that
+// "Eggs" matches "egg".
+func ingredientWords(text string) []string {
+	words := ingredientWordRE.FindAllString(strings.ToLower(text), -1)
+	for i, word := range words {
+		words[i] = singularWord(word)
+	}
+	return words
+}
+
+// singularWord returns a word without the ending of its plural, e.g. "tomato" of
+// "tomatoes". Words that only look plural, like "molasses", are kept.
+func singularWord(word string) string {
+	switch {
+	case len(word) <= 3 || strings.HasSuffix(word, "ss") || strings.HasSuffix(word, "us"):
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// containsWords returns whether words contains phrase as consecutive words.
+func containsWords(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedSuffix returns whether a name other than names[i] ends with suffix.
+func sharedSuffix(names [][]string, i int, suffix []string) bool {
+	for j, name := range names {
+		if j == i || len(name) < len(suffix) {
+			continue
+		}
+		if containsWords(name[len(name)-len(suffix):], suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package recipe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnnotateStep(t *testing.T) {
+	ingredients := IngredientList{
+		{Name: "all-purpose flour"},
+		{Name: "baking powder"},
+		{Name: "garlic powder"},
+		{Name: "eggs"},
+		{Name: "unsalted butter"},
+		{Name: "tomatoes"},
+	}
+	tests := []struct {
+		step string
+		want StepAnnotation
+	}{
+		{
+			"Preheat the oven to 350°F (180°C). Bake for 25 to 30 minutes.",
+			StepAnnotation{
+				Timers:       []Timer{{Text: "25 to 30 minutes", MinSeconds: 1500, MaxSeconds: 1800}},
+				Temperatures: []Temperature{{Text: "350°F (180°C)", Unit: Fahrenheit, Fahrenheit: 350, Celsius: 180}},
+			},
+		},
+		{
+			"Roast at 375 degrees until golden.",
+			StepAnnotation{
+				Temperatures: []Temperature{{Text: "375 degrees", Unit: Fahrenheit, Fahrenheit: 375, Celsius: 191}},
+			},
+		},
+		{
+			"Heat the oil to 180 C.",
+			StepAnnotation{
+				Temperatures: []Temperature{{Text: "180 C", Unit: Celsius, Fahrenheit: 356, Celsius: 180}},
+			},
+		},
+		{
+			"Simmer for 1 hour and 15 minutes, then cook 10 more minutes.",
+			StepAnnotation{
+				Timers: []Timer{
+					{Text: "1 hour and 15 minutes", MinSeconds: 4500, MaxSeconds: 4500},
+					{Text: "10 more minutes", MinSeconds: 600, MaxSeconds: 600},
+				},
+			},
+		},
+		{
+			"Melt the butter over medium-high heat, then reduce to low heat.",
+			StepAnnotation{
+				HeatLevels:  []HeatLevel{HeatMediumHigh, HeatLow},
+				Ingredients: []int{4},
+			},
+		},
+		{
+			"Cook for a minute, then fry for about half an hour.",
+			StepAnnotation{
+				Timers: []Timer{
+					{Text: "a minute", MinSeconds: 60, MaxSeconds: 60},
+					{Text: "half an hour", MinSeconds: 1800, MaxSeconds: 1800},
+				},
+			},
+		},
+		{
+			"Let the water cool to 110 degrees.",
+			StepAnnotation{
+				Temperatures: []Temperature{{Text: "110 degrees", Unit: Fahrenheit, Fahrenheit: 110, Celsius: 43}},
+			},
+		},
+		// Durations that are not timers, and degrees that are not temperatures.
+		{"Turn the pan 180 degrees halfway through baking.", StepAnnotation{}},
+		{"Rotate the sheet 90° and bake until golden.", StepAnnotation{}},
+		{"Cut the dough at a 45 degree angle.", StepAnnotation{}},
+		{"Flip the steaks a second time.", StepAnnotation{}},
+		{"Repeat a second time.", StepAnnotation{}},
+		{"Cook for 5 m.", StepAnnotation{}},
+		{"Rest for 10 s.", StepAnnotation{}},
+		{
+			"Whisk the flour and the powder, then beat in the egg and the tomato.",
+			StepAnnotation{Ingredients: []int{0, 3, 5}},
+		},
+		{
+			"Stir in the baking powder and garlic powder.",
+			StepAnnotation{Ingredients: []int{1, 2}},
+		},
+	}
+	for _, test := range tests {
+		got := AnnotateStep(test.step, ingredients)
+		test.want.Text = test.step
+		for _, value := range []*StepAnnotation{&got, &test.want} {
+			if len(value.Timers) == 0 {
+				value.Timers = nil
+			}
+			if len(value.Temperatures) == 0 {
+				value.Temperatures = nil
+			}
+			if len(value.HeatLevels) == 0 {
+				value.HeatLevels = nil
+			}
+			if len(value.Ingredients) == 0 {
+				value.Ingredients = nil
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("AnnotateStep(%q) = %+v, want %+v", test.step, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YieldUnit is what the quantity of a yield counts.
+type YieldUnit string
+
+const (
+	YieldServings YieldUnit = "servings"
+	YieldPieces   YieldUnit = "pieces"
+	YieldLoaves   YieldUnit = "loaves"
+	YieldCups     YieldUnit = "cups"
+	// YieldWhole counts whole dishes that are cut up to serve, like pies and cakes.
+	YieldWhole YieldUnit = "whole"
+)
+
+// yieldWord is a word that gives the unit of a yield, and how many of the unit it is.
+type yieldWord struct {
+	unit   YieldUnit
+	factor float64
+}
+
+// yieldWords are the words of a yield that have a unit other than pieces. Words that are not
+// in here, like "cookies", are counted as pieces.
+var yieldWords = map[string]yieldWord{
+	"serving":   {YieldServings, 1},
+	"servings":  {YieldServings, 1},
+	"portion":   {YieldServings, 1},
+	"portions":  {YieldServings, 1},
+	"people":    {YieldServings, 1},
+	"persons":   {YieldServings, 1},
+	"loaf":      {YieldLoaves, 1},
+	"loaves":    {YieldLoaves, 1},
+	"cup":       {YieldCups, 1},
+	"cups":      {YieldCups, 1},
+	"pint":      {YieldCups, 2},
+	"pints":     {YieldCups, 2},
+	"quart":     {YieldCups, 4},
+	"quarts":    {YieldCups, 4},
+	"gallon":    {YieldCups, 16},
+	"gallons":   {YieldCups, 16},
+	"pie":       {YieldWhole, 1},
+	"pies":      {YieldWhole, 1},
+	"cake":      {YieldWhole, 1},
+	"cakes":     {YieldWhole, 1},
+	"tart":      {YieldWhole, 1},
+	"tarts":     {YieldWhole, 1},
+	"quiche":    {YieldWhole, 1},
+	"pizza":     {YieldWhole, 1},
+	"pizzas":    {YieldWhole, 1},
+	"casserole": {YieldWhole, 1},
+}
+
+// yieldNumbers are the numbers of yields that are written as words.
+var yieldNumbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// yieldNumber matches the numbers of yields. Mixed numbers and fractions come first, so that
+// the "3" of "3/4" is not taken as a whole number.
+const yieldNumber = `\d+\s+\d+/\d+|\d+/\d+|\d+(?:\.\d+)?(?:\s*[½¼¾])?|[½¼¾]|\b(?:an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\b`
+
+// REs for the free text yields of recipes.
+var (
+	// yieldRE matches the quantity of a yield, e.g. "4", "4 to 6" or "2 dozen", and the
+	// text after it.
+	yieldRE = regexp.MustCompile(`(?i)(` + yieldNumber + `)(?:\s*(?:-|–|—|\bto\b|\bor\b)\s*(` + yieldNumber + `))?(\s+dozen\b)?([^,;()]*)`)
+	// yieldSizeRE matches the sizes of dishes, e.g. "9-inch", "(9x13-inch)" or "9x13", which
+	// are not quantities.
+	yieldSizeRE = regexp.MustCompile(`(?i)\(?\b\d+(?:\.\d+)?(?:\s*(?:x|by)\s*\d+(?:\.\d+)?)?\s*-?\s*(?:inch(?:es)?\b|in\.|"|cm\b|centimeters?\b)\)?|\(?\b\d+(?:\.\d+)?\s*[x×]\s*\d+(?:\.\d+)?\b\)?`)
+	// yieldDozenRE matches dozens without a number.
+	yieldDozenRE = regexp.MustCompile(`(?i)\b(?:half\s+a|a\s+half|half)\s+dozen\b|\ban?\s+dozen\b`)
+	// yieldServesRE matches the words that make a bare number count servings.
+	yieldServesRE = regexp.MustCompile(`(?i)\b(?:serves|servings?|feeds|portions?|people)\b`)
+)
+
+// ErrInvalidYield is returned for text that has no yield in it.
+type ErrInvalidYield struct {
+	Text string
+}
+
+func (e ErrInvalidYield) Error() string {
+	return fmt.Sprintf("no yield in %q", e.Text)
+}
+
+// ParseYield parses a yield like "4 to 6 servings", "Serves 4", "1 dozen cookies",
+// "one 9-inch pie", "2 loaves" or "Makes 24" into a quantity and a unit. A bare number
+// counts servings, unless the yield says it makes them or counts dozens, in which case it
+// counts pieces. Yields of no servings, like "0 servings", are invalid.
+func ParseYield(text string) (ServingRange, error) {
+	normalized := yieldSizeRE.ReplaceAllString(text, " ")
+	normalized = yieldDozenRE.ReplaceAllStringFunc(normalized, func(dozen string) string {
+		if strings.Contains(strings.ToLower(dozen), "half") {
+			return "6"
+		}
+		return "12"
+	})
+
+	for _, matches := range yieldRE.FindAllStringSubmatch(normalized, -1) {
+		min, ok := parseYieldNumber(matches[1])
+		if !ok {
+			continue
+		}
+		max := min
+		if matches[2] != "" {
+			if max, ok = parseYieldNumber(matches[2]); !ok {
+				continue
+			}
+		}
+		if matches[3] != "" {
+			min, max = min*12, max*12
+		}
+		if min > max {
+			min, max = max, min
+		}
+		if min <= 0 {
+			continue
+		}
+
+		item, word, known := yieldItem(matches[4])
+		article := matches[3] == "" && (strings.EqualFold(matches[1], "a") || strings.EqualFold(matches[1], "an"))
+		servingRange := newServingRange(min, max)
+		switch {
+		case known:
+			servingRange = newServingRange(min*word.factor, max*word.factor)
+			servingRange.Unit = word.unit
+			if word.unit == YieldWhole {
+				servingRange.Item = item
+			}
+		case article:
+			// "a" before a word that is not a unit is not a yield, e.g. "a crowd".
+			continue
+		case item != "":
+			servingRange.Unit, servingRange.Item = YieldPieces, item
+		case matches[3] == "" && (yieldServesRE.MatchString(normalized) || !strings.Contains(strings.ToLower(normalized), "make")):
+			servingRange.Unit = YieldServings
+		default:
+			servingRange.Unit = YieldPieces
+		}
+		return servingRange, nil
+	}
+	return ServingRange{}, ErrInvalidYield{Text: text}
+}
+
+// newServingRange returns a yield of the quantities min to max.
+func newServingRange(min, max float64) ServingRange {
+	return ServingRange{Min: wholeYield(min), Max: wholeYield(max), MinQuantity: min, MaxQuantity: max}
+}
+
+// wholeYield rounds a positive quantity to the nearest whole number, but at least 1.
+func wholeYield(quantity float64) int {
+	if whole := int(math.Round(quantity)); whole > 0 {
+		return whole
+	}
+	return 1
+}
+
+// yieldItem returns the item of the text after the quantity of a yield, e.g. "cookies" of
+// "large cookies". known is true if the item has a unit other than pieces. Only the first
+// word after any adjectives is taken, as the rest is usually a note, unless the word after
+// it is a unit, like the "servings" of "appetizer servings".
+func yieldItem(text string) (item string, word yieldWord, known bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r == '-')
+	})
+	for i, w := range words {
+		switch w {
+		case "small", "medium", "large", "mini", "big", "round", "square", "individual", "about", "approximately":
+			continue
+		case "or", "to", "and", "of", "for", "with":
+			return "", yieldWord{}, false
+		}
+		if i+1 < len(words) {
+			if next, ok := yieldWords[words[i+1]]; ok {
+				return words[i+1], next, true
+			}
+		}
+		word, known = yieldWords[w]
+		return w, word, known
+	}
+	return "", yieldWord{}, false
+}
+
+// parseYieldNumber parses a number matched by yieldNumber, like "4", "1 1/2", "2½" or "one".
+func parseYieldNumber(text string) (float64, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if value, ok := yieldNumbers[text]; ok {
+		return value, true
+	}
+
+	text = strings.NewReplacer("½", " 1/2", "¼", " 1/4", "¾", " 3/4").Replace(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return value, true
+	}
+	value, _ := ParseFraction(text)
+	return value, value >= 0
+}
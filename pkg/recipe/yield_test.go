@@ -0,0 +1,49 @@
+package recipe
+
+import "testing"
+
+func TestParseYield(t *testing.T) {
+	tests := []struct {
+		text string
+		want ServingRange
+	}{
+		{"4 servings", ServingRange{Min: 4, Max: 4, MinQuantity: 4, MaxQuantity: 4, Unit: YieldServings}},
+		{"Serves 4 to 6", ServingRange{Min: 4, Max: 6, MinQuantity: 4, MaxQuantity: 6, Unit: YieldServings}},
+		{"6-8 people", ServingRange{Min: 6, Max: 8, MinQuantity: 6, MaxQuantity: 8, Unit: YieldServings}},
+		{"4", ServingRange{Min: 4, Max: 4, MinQuantity: 4, MaxQuantity: 4, Unit: YieldServings}},
+		{"Makes 24", ServingRange{Min: 24, Max: 24, MinQuantity: 24, MaxQuantity: 24, Unit: YieldPieces}},
+		{"10 appetizer servings", ServingRange{Min: 10, Max: 10, MinQuantity: 10, MaxQuantity: 10, Unit: YieldServings}},
+		{"24 large cookies", ServingRange{Min: 24, Max: 24, MinQuantity: 24, MaxQuantity: 24, Unit: YieldPieces, Item: "cookies"}},
+		{"1 dozen cookies", ServingRange{Min: 12, Max: 12, MinQuantity: 12, MaxQuantity: 12, Unit: YieldPieces, Item: "cookies"}},
+		{"1/2 dozen cookies", ServingRange{Min: 6, Max: 6, MinQuantity: 6, MaxQuantity: 6, Unit: YieldPieces, Item: "cookies"}},
+		{"half a dozen muffins", ServingRange{Min: 6, Max: 6, MinQuantity: 6, MaxQuantity: 6, Unit: YieldPieces, Item: "muffins"}},
+		{"3/4 cup", ServingRange{Min: 1, Max: 1, MinQuantity: 0.75, MaxQuantity: 0.75, Unit: YieldCups}},
+		{"1 1/2 cups", ServingRange{Min: 2, Max: 2, MinQuantity: 1.5, MaxQuantity: 1.5, Unit: YieldCups}},
+		{"2½ cups", ServingRange{Min: 3, Max: 3, MinQuantity: 2.5, MaxQuantity: 2.5, Unit: YieldCups}},
+		{"2 pints", ServingRange{Min: 4, Max: 4, MinQuantity: 4, MaxQuantity: 4, Unit: YieldCups}},
+		{"1 quart", ServingRange{Min: 4, Max: 4, MinQuantity: 4, MaxQuantity: 4, Unit: YieldCups}},
+		{"1 gallon", ServingRange{Min: 16, Max: 16, MinQuantity: 16, MaxQuantity: 16, Unit: YieldCups}},
+		{"2 loaves", ServingRange{Min: 2, Max: 2, MinQuantity: 2, MaxQuantity: 2, Unit: YieldLoaves}},
+		{"one 9-inch pie", ServingRange{Min: 1, Max: 1, MinQuantity: 1, MaxQuantity: 1, Unit: YieldWhole, Item: "pie"}},
+		{"One 9x13 casserole", ServingRange{Min: 1, Max: 1, MinQuantity: 1, MaxQuantity: 1, Unit: YieldWhole, Item: "casserole"}},
+		{"1 (9x13-inch) cake", ServingRange{Min: 1, Max: 1, MinQuantity: 1, MaxQuantity: 1, Unit: YieldWhole, Item: "cake"}},
+	}
+	for _, test := range tests {
+		got, err := ParseYield(test.text)
+		if err != nil {
+			t.Errorf("ParseYield(%q) = %v", test.text, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseYield(%q) = %+v, want %+v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseYieldInvalid(t *testing.T) {
+	for _, text := range []string{"", "enough for a crowd", "varies", "0 servings", "Serves 0", "0 to 0 people"} {
+		if got, err := ParseYield(text); err == nil {
+			t.Errorf("ParseYield(%q) = %+v, want an error", text, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+)
+
+// HTTPConfig configures the HTTP client the scraper makes its requests with.
+type HTTPConfig struct {
+	// RequestTimeout limits a whole request, including reading the body. Zero means no limit.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// ConnectTimeout limits dialing a connection. Zero means no limit.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// Headers are sent with every request. The user agent is set by UserAgent instead.
+	Headers map[string]string `yaml:"headers"`
+	// CookieJar keeps the cookies sites set across requests.
+	CookieJar bool `yaml:"cookie_jar"`
+	// ProxyURL sends every request through a proxy, e.g. http://localhost:8080. If empty
+	// the proxy is taken from the HTTP_PROXY and HTTPS_PROXY environment variables.
+	ProxyURL string `yaml:"proxy_url"`
+}
+
+// DefaultHTTPConfig returns the HTTP client settings used when none are given.
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		RequestTimeout: time.Minute,
+		ConnectTimeout: 10 * time.Second,
+	}
+}
+
+// NewHTTPClient creates a client from cfg. If transport is nil, a copy of the default
+// transport is used, with the configured proxy and connect timeout.
+func NewHTTPClient(cfg HTTPConfig, transport http.RoundTripper) (*http.Client, error) {
+	if transport == nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.ProxyURL != "" {
+			proxy, err := url.Parse(cfg.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy url %q: %w", cfg.ProxyURL, err)
+			}
+			defaultTransport.Proxy = http.ProxyURL(proxy)
+		}
+		if cfg.ConnectTimeout > 0 {
+			dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+			defaultTransport.DialContext = dialer.DialContext
+			defaultTransport.TLSHandshakeTimeout = cfg.ConnectTimeout
+		}
+		transport = defaultTransport
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.RequestTimeout,
+	}
+	if cfg.CookieJar {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create cookie jar: %w", err)
+		}
+		client.Jar = jar
+	}
+	return client, nil
+}
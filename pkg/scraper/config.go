@@ -1,8 +1,92 @@
 package scraper
 
+import (
+	"net/http"
+	"time"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+)
+
+// DefaultUserAgent identifies the scraper to the sites it crawls.
+const DefaultUserAgent = "RecipeScraper/1.0 (+https://github.com/CS446-S23-Group35/RecipeScraper)"
+
 type Config struct {
-	StartLink  string
-	OnlyLinks  bool
-	SourceType string
-	OutputPath string
+	StartLink string `yaml:"start_link"`
+	OnlyLinks bool   `yaml:"only_links"`
+	// ChangedSince only crawls the recipes whose sitemap entry was last modified on or after
+	// it. It needs a source that crawls sitemaps. If zero, every recipe is crawled.
+	ChangedSince time.Time `yaml:"changed_since"`
+	// Include and Exclude are regular expressions of the links of the sitemaps to crawl
+	// and to leave out, added to the patterns of the source. They need a source that crawls
+	// sitemaps.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// SourceType names the source the start link is crawled with, and the source used
+	// for links whose host matches no registered source. If empty, every link is
+	// scraped with the source registered for its host.
+	SourceType string `yaml:"source_type"`
+	// Sources are the sources that can be scraped. If nil, DefaultSources is used.
+	Sources    *SourceRegistry `yaml:"-"`
+	OutputPath string          `yaml:"output_path"`
+
+	// LinksPath is where the links found while crawling are written.
+	LinksPath string `yaml:"links_path"`
+	// StatePath is the crawl state journal. A crawl with an existing journal resumes
+	// where it stopped.
+	StatePath string `yaml:"state_path"`
+	// MaxAttempts is how many times a failing link is tried across runs.
+	MaxAttempts int `yaml:"max_attempts"`
+	// SkippedLinksPath is where links skipped for policy reasons, such as robots.txt, are recorded.
+	SkippedLinksPath string `yaml:"skipped_links_path"`
+	// AppendOutput appends to the output and links files instead of truncating them,
+	// so several scrapers can share the same files.
+	AppendOutput bool `yaml:"append_output"`
+
+	// RequiredFields are the fields a recipe is not kept without, see parser.Fields. A
+	// recipe missing any other field is kept with what could be parsed. If nil, the
+	// fields of parser.DefaultFieldPolicy are required.
+	RequiredFields []string `yaml:"required_fields"`
+	// DiagnosticsPath is where the fields each recipe is missing are recorded, one JSON
+	// object per line. Empty disables it.
+	DiagnosticsPath string `yaml:"diagnostics_path"`
+	// ParseStats aggregates the parse diagnostics per field. It may be shared with other
+	// scrapers to aggregate across them, in which case it is not logged by the scraper.
+	ParseStats *ParseStats `yaml:"-"`
+	// DriftConfig stops or fails the crawl when the parser of a source stops finding a
+	// field, e.g. because the site changed its markup.
+	DriftConfig `yaml:",inline"`
+
+	// UserAgent is sent with every request and used to pick the robots.txt rules to obey.
+	UserAgent string `yaml:"user_agent"`
+
+	HTTPConfig `yaml:",inline"`
+	// Client makes the requests. If nil, a client is created from HTTPConfig, using
+	// Transport if set. Headers are added to every request either way.
+	Client    *http.Client      `yaml:"-"`
+	Transport http.RoundTripper `yaml:"-"`
+
+	ThrottleConfig `yaml:",inline"`
+	RetryConfig    `yaml:",inline"`
+	// Throttle is shared with other scrapers to limit their combined traffic. If nil,
+	// a new throttle is created from ThrottleConfig.
+	Throttle *Throttle `yaml:"-"`
+}
+
+// DefaultConfig returns the scraper configuration used when none is given.
+func DefaultConfig() Config {
+	return Config{
+		SourceType:       "foodnetwork",
+		OutputPath:       "recipes.yaml",
+		LinksPath:        "links.tmp",
+		StatePath:        "crawlstate.jsonl",
+		MaxAttempts:      3,
+		SkippedLinksPath: "skippedLinks.txt",
+		RequiredFields:   parser.DefaultFieldPolicy().Required,
+		DiagnosticsPath:  "diagnostics.jsonl",
+		DriftConfig:      DefaultDriftConfig(),
+		UserAgent:        DefaultUserAgent,
+		HTTPConfig:       DefaultHTTPConfig(),
+		ThrottleConfig:   DefaultThrottleConfig(),
+		RetryConfig:      DefaultRetryConfig(),
+	}
 }
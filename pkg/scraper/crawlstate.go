@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LinkStatus is the state of a link in a crawl.
+type LinkStatus string
+
+const (
+	StatusPending   LinkStatus = "pending"
+	StatusSucceeded LinkStatus = "succeeded"
+	StatusFailed    LinkStatus = "failed"
+	// StatusSkipped links were not fetched for policy reasons, e.g. robots.txt.
+	StatusSkipped LinkStatus = "skipped"
+	// StatusIndexed marks a start link whose index pages were all crawled.
+	StatusIndexed LinkStatus = "indexed"
+)
+
+// LinkState is what the crawl knows about a single link.
+type LinkState struct {
+	URL       string     `json:"url"`
+	Status    LinkStatus `json:"status"`
+	Attempts  int        `json:"attempts,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	// Class and StatusCode describe the last failure of a failed link.
+	Class      FailureClass `json:"class,omitempty"`
+	StatusCode int          `json:"status_code,omitempty"`
+}
+
+// CrawlState is the persistent state of a crawl. Every update is appended to a journal
+// file as one JSON line, so the state survives a crash or Ctrl-C at any point and a
+// later run resumes where the last one stopped. The journal is compacted when loaded.
+type CrawlState struct {
+	mu      sync.Mutex
+	file    *os.File
+	links   map[string]*LinkState
+	order   []string
+	encoder *json.Encoder
+}
+
+// LoadCrawlState loads the crawl state journal at path, creating it if it does not exist.
+func LoadCrawlState(path string) (*CrawlState, error) {
+	c := &CrawlState{links: make(map[string]*LinkState)}
+
+	file, err := os.Open(path)
+	switch {
+	case err == nil:
+		err = c.replay(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read crawl state %s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("could not open crawl state: %w", err)
+	}
+
+	if err := c.compact(path); err != nil {
+		return nil, fmt.Errorf("could not compact crawl state: %w", err)
+	}
+
+	c.file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open crawl state: %w", err)
+	}
+	c.encoder = json.NewEncoder(c.file)
+	return c, nil
+}
+
+// replay applies every record of a journal, later records replacing earlier ones.
+// A truncated last line, e.g. from a crash mid-write, is ignored.
+func (c *CrawlState) replay(file *os.File) error {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		state := LinkState{}
+		if err := json.Unmarshal(scanner.Bytes(), &state); err != nil || state.URL == "" {
+			continue
+		}
+		c.set(state)
+	}
+	return scanner.Err()
+}
+
+// compact atomically rewrites the journal with a single record per link.
+func (c *CrawlState) compact(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, link := range c.order {
+		if err := encoder.Encode(c.links[link]); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// set stores state in memory. c.mu must be held, or c not yet shared.
+func (c *CrawlState) set(state LinkState) {
+	if _, ok := c.links[state.URL]; !ok {
+		c.order = append(c.order, state.URL)
+	}
+	c.links[state.URL] = &state
+}
+
+// update stores state and appends it to the journal.
+func (c *CrawlState) update(state LinkState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(state)
+	return c.encoder.Encode(state)
+}
+
+// Get returns the state of link, if the crawl knows about it.
+func (c *CrawlState) Get(link string) (LinkState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.links[link]
+	if !ok {
+		return LinkState{}, false
+	}
+	return *state, true
+}
+
+// AddPending adds links the crawl does not know about yet as pending, and returns them.
+func (c *CrawlState) AddPending(links []string) ([]string, error) {
+	added := make([]string, 0, len(links))
+	for _, link := range links {
+		if _, ok := c.Get(link); ok {
+			continue
+		}
+		if err := c.update(LinkState{URL: link, Status: StatusPending}); err != nil {
+			return added, err
+		}
+		added = append(added, link)
+	}
+	return added, nil
+}
+
+// Pending returns the links that still need scraping: pending links, and failed links
+// worth retrying with fewer than maxAttempts attempts.
+func (c *CrawlState) Pending(maxAttempts int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	links := make([]string, 0)
+	for _, link := range c.order {
+		state := c.links[link]
+		if state.Status == StatusPending || (state.Status == StatusFailed && state.Class.Retryable() && state.Attempts < maxAttempts) {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// MarkSucceeded records that link was scraped.
+func (c *CrawlState) MarkSucceeded(link string) error {
+	state, _ := c.Get(link)
+	return c.update(LinkState{URL: link, Status: StatusSucceeded, Attempts: state.Attempts + 1})
+}
+
+// MarkFailed records a failed attempt at scraping link, along with how it failed.
+func (c *CrawlState) MarkFailed(link string, err error) error {
+	state, _ := c.Get(link)
+	class, statusCode := failureOf(err)
+	return c.update(LinkState{
+		URL:        link,
+		Status:     StatusFailed,
+		Attempts:   state.Attempts + 1,
+		LastError:  err.Error(),
+		Class:      class,
+		StatusCode: statusCode,
+	})
+}
+
+// MarkSkipped records that link was not fetched, and why.
+func (c *CrawlState) MarkSkipped(link, reason string) error {
+	state, _ := c.Get(link)
+	return c.update(LinkState{URL: link, Status: StatusSkipped, Attempts: state.Attempts, LastError: reason})
+}
+
+// MarkIndexed records that all index pages of the start link were crawled.
+func (c *CrawlState) MarkIndexed(startLink string) error {
+	return c.update(LinkState{URL: startLink, Status: StatusIndexed})
+}
+
+// Len returns the number of links the crawl knows about.
+func (c *CrawlState) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.links)
+}
+
+// Counts returns the number of links in each status.
+func (c *CrawlState) Counts() map[LinkStatus]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[LinkStatus]int)
+	for _, state := range c.links {
+		counts[state.Status]++
+	}
+	return counts
+}
+
+// Close closes the journal file.
+func (c *CrawlState) Close() error {
+	return c.file.Close()
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCrawlStateResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawlstate.jsonl")
+	state, err := LoadCrawlState(path)
+	if err != nil {
+		t.Fatalf("LoadCrawlState() = %v", err)
+	}
+
+	added, err := state.AddPending([]string{"a", "b", "c", "d", "e"})
+	if err != nil || !reflect.DeepEqual(added, []string{"a", "b", "c", "d", "e"}) {
+		t.Fatalf("AddPending() = %q, %v", added, err)
+	}
+	for _, update := range []error{
+		state.MarkSucceeded("a"),
+		state.MarkFailed("b", &FetchError{Link: "b", Class: FailureTransient, Err: errors.New("timeout")}),
+		state.MarkFailed("c", &FetchError{Link: "c", Class: FailurePermanent, StatusCode: 404, Err: errors.New("not found")}),
+		state.MarkFailed("d", &FetchError{Link: "d", Class: FailureBlocked, StatusCode: 403, Err: errors.New("forbidden")}),
+		state.MarkIndexed("start"),
+		state.Close(),
+	} {
+		if update != nil {
+			t.Fatalf("updating crawl state: %v", update)
+		}
+	}
+
+	// A new crawl state reads the journal of the last one.
+	state, err = LoadCrawlState(path)
+	if err != nil {
+		t.Fatalf("LoadCrawlState() = %v", err)
+	}
+	defer state.Close()
+
+	if got, want := state.Pending(3), []string{"b", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending(3) = %q, want %q", got, want)
+	}
+	if got, want := state.Pending(1), []string{"e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending(1) = %q, want %q", got, want)
+	}
+	if link, ok := state.Get("c"); !ok || link.Status != StatusFailed || link.StatusCode != 404 || link.Attempts != 1 {
+		t.Errorf("Get(c) = %+v, want a permanent failure after one attempt", link)
+	}
+	if link, ok := state.Get("start"); !ok || link.Status != StatusIndexed {
+		t.Errorf("Get(start) = %+v, want indexed", link)
+	}
+
+	// Links the crawl knows about are not added again.
+	added, err = state.AddPending([]string{"a", "e", "f"})
+	if err != nil || !reflect.DeepEqual(added, []string{"f"}) {
+		t.Errorf("AddPending() = %q, %v, want only the new link", added, err)
+	}
+	counts := state.Counts()
+	if counts[StatusPending] != 2 || counts[StatusSucceeded] != 1 || counts[StatusFailed] != 3 {
+		t.Errorf("Counts() = %v", counts)
+	}
+}
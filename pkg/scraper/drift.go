@@ -0,0 +1,210 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+)
+
+// The actions taken when a field drifts.
+const (
+	// DriftAbort stops the crawl, leaving the remaining links pending.
+	DriftAbort = "abort"
+	// DriftAlert logs the drift and finishes the crawl, which then fails.
+	DriftAlert = "alert"
+)
+
+// DriftConfig configures the detection of selector drift: a site changing its markup so
+// that its parser stops finding a field, which would otherwise fail every recipe quietly.
+type DriftConfig struct {
+	// DriftWindow is the number of most recent recipes of a source a field's success
+	// rate is taken over. 0 disables drift detection.
+	DriftWindow int `yaml:"drift_window"`
+	// DriftThreshold is the share of the window a field must be found in, from 0 to 1.
+	DriftThreshold float64 `yaml:"drift_threshold"`
+	// DriftFields are the fields that are tracked. If nil, the required fields are.
+	DriftFields []string `yaml:"drift_fields"`
+	// DriftAction is what happens when a field drifts, abort or alert. Either way the
+	// scrape returns a DriftError.
+	DriftAction string `yaml:"drift_action"`
+	// DriftSamples is the number of pages that missed a field that are kept, and saved
+	// to DriftSampleDir when the field drifts.
+	DriftSamples   int    `yaml:"drift_samples"`
+	DriftSampleDir string `yaml:"drift_sample_dir"`
+}
+
+// DefaultDriftConfig returns the drift detection used when none is given.
+func DefaultDriftConfig() DriftConfig {
+	return DriftConfig{
+		DriftWindow:    50,
+		DriftThreshold: 0.5,
+		DriftAction:    DriftAbort,
+		DriftSamples:   5,
+		DriftSampleDir: "drift",
+	}
+}
+
+// Validate returns an error if the action, threshold or fields are invalid.
+func (c DriftConfig) Validate() error {
+	if c.DriftAction != DriftAbort && c.DriftAction != DriftAlert {
+		return fmt.Errorf("unknown drift action %q, use %s or %s", c.DriftAction, DriftAbort, DriftAlert)
+	}
+	if c.DriftThreshold < 0 || c.DriftThreshold > 1 {
+		return fmt.Errorf("drift threshold %v is not between 0 and 1", c.DriftThreshold)
+	}
+	return parser.FieldPolicy{Required: c.DriftFields}.Validate()
+}
+
+// DriftSample is a page that missed a drifted field.
+type DriftSample struct {
+	Link string
+	// Path is where the HTML of the page was saved, empty if it could not be.
+	Path string
+	page []byte
+}
+
+// DriftError is returned by a scrape in which a field of a source was found in fewer of
+// the recent recipes than the threshold.
+type DriftError struct {
+	Source string
+	Field  string
+	// Rate is the share of the window the field was found in.
+	Rate    float64
+	Window  int
+	Samples []DriftSample
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("selector drift: %s of source %s found in %.0f%% of the last %d recipes",
+		e.Field, e.Source, e.Rate*100, e.Window)
+}
+
+// driftDetector tracks the success of fields per source over a sliding window. It is
+// safe for concurrent use.
+type driftDetector struct {
+	cfg    DriftConfig
+	mu     sync.Mutex
+	fields map[string]*fieldWindow
+}
+
+// fieldWindow is the sliding window of a field of a source.
+type fieldWindow struct {
+	found   []bool
+	next    int
+	full    bool
+	samples []DriftSample
+	// drifted is set while the field is below the threshold, so a drift is reported once.
+	drifted bool
+}
+
+func newDriftDetector(cfg DriftConfig) *driftDetector {
+	return &driftDetector{cfg: cfg, fields: make(map[string]*fieldWindow)}
+}
+
+// Record adds the diagnostics of a page of source to the windows of the tracked fields.
+// It returns a DriftError if a field has just dropped below the threshold. Fields are
+// tracked for the first parser of a source, even if a fallback parser parsed the page.
+func (d *driftDetector) Record(source, link string, page []byte, diagnostics *parser.Diagnostics) *DriftError {
+	if d.cfg.DriftWindow < 1 || diagnostics == nil {
+		return nil
+	}
+	diagnostics = diagnostics.PrimaryReport()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var drift *DriftError
+	for _, field := range d.cfg.DriftFields {
+		key := source + "\x00" + field
+		window, ok := d.fields[key]
+		if !ok {
+			window = &fieldWindow{found: make([]bool, d.cfg.DriftWindow)}
+			d.fields[key] = window
+		}
+
+		found := diagnostics.Status(field) == parser.FieldFound
+		window.found[window.next] = found
+		window.next = (window.next + 1) % len(window.found)
+		window.full = window.full || window.next == 0
+		if !found && d.cfg.DriftSamples > 0 {
+			if len(window.samples) == d.cfg.DriftSamples {
+				window.samples = window.samples[1:]
+			}
+			window.samples = append(window.samples, DriftSample{Link: link, page: page})
+		}
+		if !window.full {
+			continue
+		}
+
+		rate := window.rate()
+		if rate >= d.cfg.DriftThreshold {
+			window.drifted = false
+			continue
+		}
+		if window.drifted || drift != nil {
+			continue
+		}
+		window.drifted = true
+		drift = &DriftError{
+			Source:  source,
+			Field:   field,
+			Rate:    rate,
+			Window:  len(window.found),
+			Samples: append([]DriftSample(nil), window.samples...),
+		}
+	}
+	return drift
+}
+
+// rate returns the share of the window the field was found in.
+func (w *fieldWindow) rate() float64 {
+	found := 0
+	for _, ok := range w.found {
+		if ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(w.found))
+}
+
+// unsafeFileRE matches the characters left out of sample file names.
+var unsafeFileRE = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// saveSamples writes the HTML of the samples of a drift to dir, and sets their paths.
+func (e *DriftError) saveSamples(dir string) error {
+	if dir == "" || len(e.Samples) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create drift sample directory: %w", err)
+	}
+	prefix := unsafeFileRE.ReplaceAllString(e.Source+"-"+e.Field, "_")
+	for i := range e.Samples {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%d.html", prefix, i+1))
+		// The link goes first so the page can be traced back to where it came from.
+		page := append([]byte("<!-- "+strings.ReplaceAll(e.Samples[i].Link, "--", "%2D%2D")+" -->\n"), e.Samples[i].page...)
+		if err := os.WriteFile(path, page, 0644); err != nil {
+			return fmt.Errorf("could not save drift sample: %w", err)
+		}
+		e.Samples[i].Path = path
+	}
+	return nil
+}
+
+// log logs the drift and its samples.
+func (e *DriftError) log() {
+	log.Println("ALERT " + e.Error())
+	for _, sample := range e.Samples {
+		if sample.Path != "" {
+			log.Printf("  sample %s saved to %s", sample.Link, sample.Path)
+		} else {
+			log.Printf("  sample %s", sample.Link)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// FailureClass says why scraping a link failed, and so whether it is worth retrying.
+type FailureClass string
+
+const (
+	// FailureTransient is a failure that may go away, e.g. 429, 5xx or a timeout.
+	FailureTransient FailureClass = "transient"
+	// FailurePermanent is a failure that will not go away, e.g. 404 or 410.
+	FailurePermanent FailureClass = "permanent"
+	// FailureBlocked is a soft block, e.g. a captcha or access denied page.
+	FailureBlocked FailureClass = "blocked"
+	// FailureParse is a page that was fetched but could not be parsed.
+	FailureParse FailureClass = "parse"
+)
+
+// Retryable reports whether a link that failed this way should be queued again. A
+// blocked link is not, as retrying a site that refused us only adds to its load. Unknown
+// classes, e.g. from an older crawl state, are retried.
+func (c FailureClass) Retryable() bool {
+	return c != FailurePermanent && c != FailureParse && c != FailureBlocked
+}
+
+// RetryConfig configures how fetches that fail transiently are retried.
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries     int           `yaml:"max_retries"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// DefaultRetryConfig returns the retry policy used when none is given.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     5 * time.Minute,
+	}
+}
+
+// FetchError is returned when a page could not be fetched.
+type FetchError struct {
+	Link       string
+	StatusCode int
+	Class      FailureClass
+	// RetryAfter is the delay the server asked for, if any.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s fetch failure for %s: status %d: %s", e.Class, e.Link, e.StatusCode, e.Err.Error())
+	}
+	return fmt.Sprintf("%s fetch failure for %s: %s", e.Class, e.Link, e.Err.Error())
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Markers of pages that are served instead of the real page to block bots.
+var softBlockRE = regexp.MustCompile(`(?i)<title>\s*(access denied|attention required|just a moment|are you a robot|robot check|captcha)|px-captcha|cf-challenge|challenge-platform|please verify you are a human`)
+
+// classifyResponse returns a FetchError if a response is not a usable page.
+func classifyResponse(link string, resp *http.Response, body []byte) *FetchError {
+	fetchErr := &FetchError{Link: link, StatusCode: resp.StatusCode, Err: errors.New(resp.Status)}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		fetchErr.Class = FailureTransient
+		fetchErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case code == http.StatusRequestTimeout || code >= 500:
+		fetchErr.Class = FailureTransient
+	case code == http.StatusForbidden:
+		fetchErr.Class = FailureBlocked
+	case code >= 400:
+		fetchErr.Class = FailurePermanent
+	case softBlockRE.Match(body):
+		fetchErr.Class = FailureBlocked
+		fetchErr.Err = errors.New("soft block page")
+	default:
+		return nil
+	}
+	return fetchErr
+}
+
+// classifyError wraps an error from the HTTP client in a FetchError. Connection errors
+// and timeouts are transient.
+func classifyError(link string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrDisallowedByRobots) {
+		return err
+	}
+
+	class := FailurePermanent
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.As(err, &opErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, context.DeadlineExceeded):
+		class = FailureTransient
+	}
+	return &FetchError{Link: link, Class: class, Err: err}
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as a date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// failureOf returns the class and status code of a scraping error. A link no source
+// can parse fails permanently.
+func failureOf(err error) (FailureClass, int) {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Class, fetchErr.StatusCode
+	}
+	if errors.Is(err, ErrUnknownSource) {
+		return FailurePermanent, 0
+	}
+	return FailureParse, 0
+}
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		body       string
+		wantClass  FailureClass
+		wantWait   time.Duration
+	}{
+		{http.StatusOK, "", "<html><title>Pancakes</title></html>", "", 0},
+		{http.StatusTooManyRequests, "120", "", FailureTransient, 2 * time.Minute},
+		{http.StatusServiceUnavailable, "", "", FailureTransient, 0},
+		{http.StatusInternalServerError, "", "", FailureTransient, 0},
+		{http.StatusRequestTimeout, "", "", FailureTransient, 0},
+		{http.StatusForbidden, "", "", FailureBlocked, 0},
+		{http.StatusNotFound, "", "", FailurePermanent, 0},
+		{http.StatusGone, "", "", FailurePermanent, 0},
+		{http.StatusOK, "", "<html><title>Just a moment...</title></html>", FailureBlocked, 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Status: http.StatusText(test.status), Header: http.Header{}}
+		if test.retryAfter != "" {
+			resp.Header.Set("Retry-After", test.retryAfter)
+		}
+		fetchErr := classifyResponse("https://www.example.com/", resp, []byte(test.body))
+		if test.wantClass == "" {
+			if fetchErr != nil {
+				t.Errorf("status %d: classifyResponse() = %v, want nil", test.status, fetchErr)
+			}
+			continue
+		}
+		if fetchErr == nil || fetchErr.Class != test.wantClass || fetchErr.RetryAfter != test.wantWait {
+			t.Errorf("status %d: classifyResponse() = %+v, want class %s waiting %s", test.status, fetchErr, test.wantClass, test.wantWait)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	if err := classifyError("https://www.example.com/", context.Canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("classifyError(context.Canceled) = %v, want context.Canceled", err)
+	}
+	var fetchErr *FetchError
+	err := classifyError("https://www.example.com/", context.DeadlineExceeded)
+	if !errors.As(err, &fetchErr) || fetchErr.Class != FailureTransient {
+		t.Errorf("classifyError(context.DeadlineExceeded) = %v, want a transient failure", err)
+	}
+	err = classifyError("https://www.example.com/", errors.New("unsupported protocol scheme"))
+	if !errors.As(err, &fetchErr) || fetchErr.Class != FailurePermanent {
+		t.Errorf("classifyError() = %v, want a permanent failure", err)
+	}
+}
+
+func TestFailureClassRetryable(t *testing.T) {
+	tests := []struct {
+		class FailureClass
+		want  bool
+	}{
+		{FailureTransient, true},
+		{FailurePermanent, false},
+		{FailureBlocked, false},
+		{FailureParse, false},
+		{FailureClass("unknown"), true},
+	}
+	for _, test := range tests {
+		if got := test.class.Retryable(); got != test.want {
+			t.Errorf("%s.Retryable() = %t, want %t", test.class, got, test.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("parseRetryAfter(30) = %s, want 30s", got)
+	}
+	for _, value := range []string{"", "0", "-5", "soon", "Mon, 02 Jan 2006 15:04:05 GMT"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", value, got)
+		}
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, want about an hour", date, got)
+	}
+}
@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+)
+
+// FieldStats counts how a field was filled in across the recipes of a crawl.
+type FieldStats struct {
+	Field     string
+	Found     int
+	Missing   int
+	Defaulted int
+	Warnings  int
+	// Rejected counts the recipes that were not kept because the field was required
+	// and not found.
+	Rejected int
+}
+
+// ParseStats aggregates the parse diagnostics of a crawl per field, so that fields a
+// site stopped showing, or a parser stopped finding, stand out. It is safe for
+// concurrent use, and may be shared by several scrapers.
+type ParseStats struct {
+	mu       sync.Mutex
+	parsed   int
+	rejected int
+	fields   map[string]*FieldStats
+}
+
+// NewParseStats creates empty parse statistics.
+func NewParseStats() *ParseStats {
+	return &ParseStats{fields: make(map[string]*FieldStats)}
+}
+
+// Record adds the diagnostics of a parsed page, and the error it was rejected with, if any.
+func (s *ParseStats) Record(diagnostics *parser.Diagnostics, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.parsed++
+	if err != nil {
+		s.rejected++
+		var parseErr parser.ErrParseFailed
+		if errors.As(err, &parseErr) {
+			s.field(parseErr.Field).Rejected++
+		}
+	}
+	if diagnostics == nil {
+		return
+	}
+
+	for _, report := range diagnostics.Fields {
+		stats := s.field(report.Field)
+		switch report.Status {
+		case parser.FieldFound:
+			stats.Found++
+		case parser.FieldMissing:
+			stats.Missing++
+		case parser.FieldDefaulted:
+			stats.Defaulted++
+		}
+	}
+	for _, warning := range diagnostics.Warnings {
+		s.field(warning.Field).Warnings++
+	}
+}
+
+// field returns the stats of field, creating them if needed. s.mu must be held.
+func (s *ParseStats) field(field string) *FieldStats {
+	stats, ok := s.fields[field]
+	if !ok {
+		stats = &FieldStats{Field: field}
+		s.fields[field] = stats
+	}
+	return stats
+}
+
+// Fields returns the stats of every field, in the order of a recipe. Fields parsers do not
+// normally report on come last.
+func (s *ParseStats) Fields() []FieldStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order := make(map[string]int, len(parser.Fields))
+	for i, field := range parser.Fields {
+		order[field] = i
+	}
+	fields := make([]FieldStats, 0, len(s.fields))
+	for _, stats := range s.fields {
+		fields = append(fields, *stats)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, aKnown := order[fields[i].Field]
+		b, bKnown := order[fields[j].Field]
+		if aKnown != bKnown {
+			return aKnown
+		}
+		if aKnown {
+			return a < b
+		}
+		return fields[i].Field < fields[j].Field
+	})
+	return fields
+}
+
+// Counts returns the number of pages parsed, and how many of them were rejected.
+func (s *ParseStats) Counts() (parsed, rejected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.parsed, s.rejected
+}
+
+// Log logs the number of recipes parsed, and the fields that were not always found.
+func (s *ParseStats) Log() {
+	parsed, rejected := s.Counts()
+	log.Printf("Parse stats: %d pages parsed, %d recipes kept, %d rejected", parsed, parsed-rejected, rejected)
+	for _, stats := range s.Fields() {
+		if stats.Missing+stats.Defaulted+stats.Warnings+stats.Rejected == 0 {
+			continue
+		}
+		log.Printf("  %s: %d found, %d missing, %d defaulted, %d warnings, %d rejected",
+			stats.Field, stats.Found, stats.Missing, stats.Defaulted, stats.Warnings, stats.Rejected)
+	}
+}
@@ -0,0 +1,257 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDisallowedByRobots is returned when robots.txt does not allow fetching a link.
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	// ErrRobotsUnavailable is returned for the links of a host whose robots.txt could not
+	// be fetched. The links are not failures, they may be fetched once it can be.
+	ErrRobotsUnavailable = errors.New("robots.txt unreachable")
+)
+
+const (
+	// robotsCacheTime is how long a fetched robots.txt is trusted.
+	robotsCacheTime   = 24 * time.Hour
+	robotsMaxFileSize = 500 * 1024
+)
+
+// robotsRetryTime is how long to wait before trying again to fetch a robots.txt that could
+// not be fetched.
+var robotsRetryTime = time.Minute
+
+// robotsRules are the rules of a robots.txt file that apply to one user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	path    string
+	pattern *regexp.Regexp
+	allow   bool
+}
+
+var allowAll = &robotsRules{}
+
+// Allowed reports whether path (including its query) may be fetched. The longest
+// matching rule wins, and Allow wins a tie.
+func (r *robotsRules) Allowed(path string) bool {
+	allowed := true
+	matchLen := -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if len(rule.path) > matchLen || (len(rule.path) == matchLen && rule.allow) {
+			allowed = rule.allow
+			matchLen = len(rule.path)
+		}
+	}
+	return allowed
+}
+
+// parseRobots parses a robots.txt file and returns the rules of the group of the product
+// token of userAgent, falling back to the * group.
+func parseRobots(reader io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(robotsToken(userAgent))
+
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	groups := make([]*group, 0)
+	sitemaps := make([]string, 0)
+
+	var cur *group
+	inAgents := false
+	scanner := bufio.NewScanner(io.LimitReader(reader, robotsMaxFileSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share the same group.
+			if !inAgents {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		case "allow", "disallow":
+			// An empty Disallow allows everything, which is the default anyway.
+			if cur != nil && value != "" {
+				cur.rules.rules = append(cur.rules.rules, robotsRule{
+					path:    value,
+					pattern: robotsPattern(value),
+					allow:   key == "allow",
+				})
+			}
+		case "crawl-delay":
+			if cur != nil {
+				seconds, err := strconv.ParseFloat(value, 64)
+				if err == nil && seconds > 0 {
+					cur.rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+
+	// Pick the group of our product token, matched case insensitively, or the * group.
+	var best, wildcard *group
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			switch {
+			case best == nil && token != "" && agent == token:
+				best = g
+			case wildcard == nil && agent == "*":
+				wildcard = g
+			}
+		}
+	}
+	if best == nil {
+		best = wildcard
+	}
+
+	rules := &robotsRules{}
+	if best != nil {
+		rules = &best.rules
+	}
+	rules.sitemaps = sitemaps
+	return rules
+}
+
+// robotsToken returns the product token of a user agent, e.g. RecipeScraper for
+// "RecipeScraper/1.0 (+https://...)".
+func robotsToken(userAgent string) string {
+	token := strings.Fields(userAgent)
+	if len(token) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(token[0], "/")
+	return name
+}
+
+// robotsPattern compiles a robots.txt path, where * matches anything and a trailing
+// $ anchors the end of the path.
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(path), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// robotsCache fetches and caches the robots.txt rules of every host the scraper visits.
+type robotsCache struct {
+	userAgent string
+	// fetch makes the request for a robots.txt file.
+	fetch func(ctx context.Context, link string) (*http.Response, error)
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	mu      sync.Mutex
+	rules   *robotsRules
+	err     error
+	expires time.Time
+}
+
+func newRobotsCache(userAgent string, fetch func(ctx context.Context, link string) (*http.Response, error)) *robotsCache {
+	return &robotsCache{
+		userAgent: userAgent,
+		fetch:     fetch,
+		hosts:     make(map[string]*robotsEntry),
+	}
+}
+
+// rulesFor returns the rules that apply to u, fetching robots.txt for its host if needed.
+// The boolean is true if the rules were fetched by this call. An unreachable robots.txt
+// means the host may not be crawled for now, which returns a transient *FetchError.
+func (c *robotsCache) rulesFor(ctx context.Context, u *url.URL) (*robotsRules, bool, error) {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		c.hosts[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if time.Now().Before(entry.expires) {
+		return entry.rules, false, entry.err
+	}
+
+	link := key + "/robots.txt"
+	rules, err := c.fetchRules(ctx, link)
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+	if err != nil {
+		log.Printf("could not fetch robots.txt for %s, not crawling it for now: %s", u.Host, err.Error())
+		entry.rules = nil
+		entry.err = &FetchError{Link: link, Class: FailureTransient, Err: fmt.Errorf("%w: %s", ErrRobotsUnavailable, err.Error())}
+		entry.expires = time.Now().Add(robotsRetryTime)
+		return nil, true, entry.err
+	}
+
+	entry.rules = rules
+	entry.err = nil
+	entry.expires = time.Now().Add(robotsCacheTime)
+	return entry.rules, true, nil
+}
+
+// fetchRules fetches a robots.txt file. A missing file allows everything.
+func (c *robotsCache) fetchRules(ctx context.Context, link string) (*robotsRules, error) {
+	resp, err := c.fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(resp.Body, c.userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return allowAll, nil
+	default:
+		return nil, errors.New("bad status code: " + resp.Status)
+	}
+}
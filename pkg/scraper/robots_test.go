@@ -0,0 +1,136 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+User-agent: Scraper
+Disallow: /
+
+User-agent: recipescraper
+Disallow: /private/
+Allow: /private/recipes/
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /search
+
+Sitemap: https://www.example.com/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		userAgent  string
+		allowed    []string
+		disallowed []string
+		crawlDelay time.Duration
+	}{
+		{
+			"RecipeScraper/1.0 (+https://example.com)",
+			[]string{"/", "/search", "/private/recipes/pie"},
+			[]string{"/private/", "/private/account"},
+			2 * time.Second,
+		},
+		{"RECIPESCRAPER", []string{"/search"}, []string{"/private/"}, 2 * time.Second},
+		// A token that only contains the name of a group is not in that group.
+		{"MyScraperBot/2.0", []string{"/", "/private/"}, []string{"/search", "/search?q=pie"}, 0},
+		{"", []string{"/private/"}, []string{"/search"}, 0},
+	}
+	for _, test := range tests {
+		rules := parseRobots(strings.NewReader(testRobots), test.userAgent)
+		for _, path := range test.allowed {
+			if !rules.Allowed(path) {
+				t.Errorf("%q: %s is disallowed, want allowed", test.userAgent, path)
+			}
+		}
+		for _, path := range test.disallowed {
+			if rules.Allowed(path) {
+				t.Errorf("%q: %s is allowed, want disallowed", test.userAgent, path)
+			}
+		}
+		if rules.crawlDelay != test.crawlDelay {
+			t.Errorf("%q: crawl delay = %s, want %s", test.userAgent, rules.crawlDelay, test.crawlDelay)
+		}
+		if want := []string{"https://www.example.com/sitemap.xml"}; !reflect.DeepEqual(rules.sitemaps, want) {
+			t.Errorf("%q: sitemaps = %q, want %q", test.userAgent, rules.sitemaps, want)
+		}
+	}
+}
+
+func TestRobotsPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		match   []string
+		noMatch []string
+	}{
+		{"/private", []string{"/private", "/private/a", "/privateer"}, []string{"/", "/a/private"}},
+		{"/*.pdf$", []string{"/a.pdf", "/a/b.pdf"}, []string{"/a.pdf?x=1", "/a.pdfs"}},
+		{"/*?sort=", []string{"/recipes?sort=new"}, []string{"/recipes"}},
+	}
+	for _, test := range tests {
+		pattern := robotsPattern(test.path)
+		for _, path := range test.match {
+			if !pattern.MatchString(path) {
+				t.Errorf("robotsPattern(%q) does not match %s", test.path, path)
+			}
+		}
+		for _, path := range test.noMatch {
+			if pattern.MatchString(path) {
+				t.Errorf("robotsPattern(%q) matches %s", test.path, path)
+			}
+		}
+	}
+}
+
+func TestScrapeAllRequeuesLinksOfUnreachableRobots(t *testing.T) {
+	defer func(retryTime time.Duration) { robotsRetryTime = retryTime }(robotsRetryTime)
+	robotsRetryTime = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	robotsRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			mu.Lock()
+			robotsRequests++
+			first := robotsRequests == 1
+			mu.Unlock()
+			if first {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case "/recipe":
+			w.Write([]byte(testRecipePage))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := newTestScraper(t)
+	link := server.URL + "/recipe"
+	if _, err := s.state.AddPending([]string{link}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.scrapeAll(context.Background(), []string{link}); err != nil {
+		t.Fatalf("scrapeAll() = %v", err)
+	}
+	state, ok := s.state.Get(link)
+	if !ok || state.Status != StatusSucceeded || state.Attempts > 1 {
+		t.Errorf("link state = %+v, want succeeded in one attempt", state)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if robotsRequests != 2 {
+		t.Errorf("robots.txt was requested %d times, want 2", robotsRequests)
+	}
+}
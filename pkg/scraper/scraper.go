@@ -1,99 +1,381 @@
 package scraper
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/CS446-S23-Group35/RecipeScraper/pkg/linksource"
 	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
 	"github.com/CS446-S23-Group35/RecipeScraper/pkg/recipe"
+	"github.com/cenkalti/backoff/v4"
 	"golang.org/x/net/html"
 	yaml "gopkg.in/yaml.v3"
 )
 
 type Scraper struct {
+	sources *SourceRegistry
+	// source is the configured source, nil if sources are picked by host.
+	source    *Source
+	writer    io.WriteCloser
+	startLink string
+	onlyLinks bool
+	// linkSource finds the recipe links of the index pages from the start link.
 	linkSource linksource.LinkSource
-	parser     parser.Parser
-	writer     io.WriteCloser
-	startLink  string
-	onlyLinks  bool
+
+	linksPath    string
+	appendOutput bool
+
+	throttle    *Throttle
+	writerMutex sync.Mutex
+
+	client        *http.Client
+	headers       map[string]string
+	userAgent     string
+	robots        *robotsCache
+	skippedWriter io.WriteCloser
+	skippedMutex  sync.Mutex
+
+	state       *CrawlState
+	maxAttempts int
+	retry       RetryConfig
+
+	fieldPolicy       parser.FieldPolicy
+	parseStats        *ParseStats
+	ownParseStats     bool
+	diagnosticsWriter io.WriteCloser
+	diagnosticsMutex  sync.Mutex
+
+	drift       *driftDetector
+	driftAction string
+	driftDir    string
+	// driftErr is the first drift of the crawl, and cancel stops the crawl on drift.
+	driftErr   *DriftError
+	cancel     context.CancelFunc
+	driftMutex sync.Mutex
 }
 
-func NewScraper(cfg Config) *Scraper {
-	outputFile, err := os.Create(cfg.OutputPath)
+const (
+	// maxPageSize is the most that is read of a single page.
+	maxPageSize = 10 * 1024 * 1024
+	// maxSitemapSize is the most that is read of a sitemap, the size limit of the sitemap
+	// protocol.
+	maxSitemapSize = 50 * 1024 * 1024
+)
+
+// NewScraper creates a scraper for the configured source type, or returns an error if the
+// source type is unknown, the start link has no source that can crawl it, or the output
+// files cannot be created. If the crawl state file
+// already has links, the scraper resumes that crawl and appends to the output files.
+func NewScraper(cfg Config) (*Scraper, error) {
+	s := &Scraper{
+		sources:      cfg.Sources,
+		startLink:    cfg.StartLink,
+		onlyLinks:    cfg.OnlyLinks,
+		linksPath:    cfg.LinksPath,
+		appendOutput: cfg.AppendOutput,
+		throttle:     cfg.Throttle,
+		client:       cfg.Client,
+		headers:      cfg.Headers,
+		userAgent:    cfg.UserAgent,
+		maxAttempts:  cfg.MaxAttempts,
+		retry:        cfg.RetryConfig,
+		fieldPolicy:  parser.FieldPolicy{Required: cfg.RequiredFields},
+		parseStats:   cfg.ParseStats,
+	}
+	if cfg.RequiredFields == nil {
+		s.fieldPolicy = parser.DefaultFieldPolicy()
+	}
+	if err := s.fieldPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if s.parseStats == nil {
+		s.parseStats = NewParseStats()
+		s.ownParseStats = true
+	}
+	driftCfg := cfg.DriftConfig
+	if driftCfg.DriftFields == nil {
+		driftCfg.DriftFields = s.fieldPolicy.Required
+	}
+	if driftCfg.DriftWindow > 0 {
+		if err := driftCfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	s.drift = newDriftDetector(driftCfg)
+	s.driftAction = driftCfg.DriftAction
+	s.driftDir = driftCfg.DriftSampleDir
+	if s.throttle == nil {
+		s.throttle = NewThrottle(cfg.ThrottleConfig)
+	}
+	if s.client == nil {
+		client, err := NewHTTPClient(cfg.HTTPConfig, cfg.Transport)
+		if err != nil {
+			return nil, err
+		}
+		s.client = client
+	}
+	if s.userAgent == "" {
+		s.userAgent = DefaultUserAgent
+	}
+	if s.maxAttempts < 1 {
+		s.maxAttempts = 1
+	}
+	s.robots = newRobotsCache(s.userAgent, s.fetch)
+
+	if s.sources == nil {
+		s.sources = DefaultSources
+	}
+	if cfg.SourceType != "" {
+		source, err := s.sources.Lookup(cfg.SourceType)
+		if err != nil {
+			return nil, err
+		}
+		s.source = source
+	}
+	if s.startLink != "" {
+		source, err := s.indexSource()
+		if err != nil {
+			return nil, err
+		}
+		if source.LinkSource == nil {
+			return nil, fmt.Errorf("source %q cannot crawl index pages", source.Name)
+		}
+		s.linkSource = source.LinkSource
+		if !cfg.ChangedSince.IsZero() || len(cfg.Include) > 0 || len(cfg.Exclude) > 0 {
+			sitemaps, ok := source.LinkSource.(*linksource.SitemapLinkSource)
+			if !ok {
+				return nil, fmt.Errorf("source %q does not crawl sitemaps, so it cannot filter recipes by date or link pattern", source.Name)
+			}
+			sitemaps, err := sitemaps.WithLinkPatterns(cfg.Include, cfg.Exclude)
+			if err != nil {
+				return nil, fmt.Errorf("source %q: %w", source.Name, err)
+			}
+			s.linkSource = sitemaps.WithChangedSince(cfg.ChangedSince)
+		}
+	}
+
+	state, err := LoadCrawlState(cfg.StatePath)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	s.state = state
+	if state.Len() > 0 {
+		s.appendOutput = true
 	}
 
-	switch cfg.SourceType {
-	case "foodnetwork":
-		return &Scraper{
-			linkSource: linksource.NewFoodnetworkLinkSource(),
-			parser:     parser.NewFoodnetworkParser(),
-			writer:     outputFile,
-			startLink:  cfg.StartLink,
-			onlyLinks:  cfg.OnlyLinks,
+	skippedFile, err := createFile(cfg.SkippedLinksPath, s.appendOutput)
+	if err != nil {
+		state.Close()
+		return nil, fmt.Errorf("could not create skipped links file: %w", err)
+	}
+	s.skippedWriter = skippedFile
+
+	if cfg.DiagnosticsPath != "" {
+		diagnosticsFile, err := createFile(cfg.DiagnosticsPath, s.appendOutput)
+		if err != nil {
+			state.Close()
+			skippedFile.Close()
+			return nil, fmt.Errorf("could not create diagnostics file: %w", err)
 		}
+		s.diagnosticsWriter = diagnosticsFile
 	}
-	return nil
+
+	outputFile, err := createFile(cfg.OutputPath, s.appendOutput)
+	if err != nil {
+		state.Close()
+		skippedFile.Close()
+		if s.diagnosticsWriter != nil {
+			s.diagnosticsWriter.Close()
+		}
+		return nil, fmt.Errorf("could not create output file: %w", err)
+	}
+	s.writer = outputFile
+
+	return s, nil
 }
 
+// createFile creates the file at path and its parent directories. If appendTo is
+// set an existing file is appended to instead of truncated.
+func createFile(path string, appendTo bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if appendTo {
+		return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	}
+	return os.Create(path)
+}
+
+// close closes the output files and the crawl state, and returns the first error.
+func (s *Scraper) close() error {
+	closers := []io.Closer{s.writer, s.skippedWriter, s.state}
+	if s.diagnosticsWriter != nil {
+		closers = append(closers, s.diagnosticsWriter)
+	}
+	var firstErr error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Scrape crawls the index pages from the start link and scrapes the recipes found. If the
+// index pages were already crawled by an earlier run, only the remaining recipes are scraped.
 func (s *Scraper) Scrape(ctx context.Context) error {
-	defer s.writer.Close()
+	defer s.close()
+
+	if state, ok := s.state.Get(s.startLink); ok && state.Status == StatusIndexed {
+		log.Println("Index pages already crawled, resuming from crawl state: " + s.startLink)
+	} else if err := s.crawlIndex(ctx); err != nil {
+		return err
+	}
+
+	if s.onlyLinks {
+		return nil
+	}
+
+	log.Println("Starting scraping recipes")
+	if err := s.scrapeAll(ctx, s.state.Pending(s.maxAttempts)); err != nil {
+		return err
+	}
+
+	log.Println("Finishing scraping with Success!")
+	return nil
+}
 
-	linkFile, err := os.Create("links.tmp")
+// crawlIndex follows the index pages from the start link, writes the recipe links found to
+// the links file and adds them to the crawl state. Every index page and link is visited
+// once, even if several index pages lead to it. Sitemaps that fail are recorded as skipped,
+// and the crawl only fails if all of them do.
+func (s *Scraper) crawlIndex(ctx context.Context) error {
+	linkFile, err := createFile(s.linksPath, s.appendOutput)
 	if err != nil {
 		return fmt.Errorf("could not create links temp file: %w", err)
 	}
 	defer linkFile.Close()
 
 	links := make([]string, 0, 100000)
+	seenLinks := make(map[string]bool)
+
+	queue, err := s.startPages(ctx)
+	if err != nil {
+		return err
+	}
+	seenPages := make(map[string]bool)
+	for _, page := range queue {
+		seenPages[page] = true
+	}
+	// A sitemap that fails is skipped, as a site lists many and one of them may be gone.
+	_, skipFailed := s.linkSource.(linksource.PageLinkSource)
+	var lastErr error
+	crawled := 0
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		curLink := queue[0]
+		queue = queue[1:]
 
-	curLink := s.startLink
-	for {
 		log.Println("Doing page: " + curLink)
 		linkPage, err := s.scrapeForLink(ctx, curLink)
+		if err != nil && skipFailed && ctx.Err() == nil {
+			if !errors.Is(err, ErrDisallowedByRobots) {
+				s.recordSkipped(curLink, err.Error())
+			}
+			lastErr = err
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("error scraping for link: %w", err)
 		}
+		crawled++
 
-		links = append(links, linkPage.Links...)
+		for _, link := range linkPage.Links {
+			if !seenLinks[link] {
+				seenLinks[link] = true
+				links = append(links, link)
+			}
+		}
 
-		if linkPage.NextPage == "" {
-			break
+		for _, page := range append([]string{linkPage.NextPage}, linkPage.IndexPages...) {
+			if page != "" && !seenPages[page] {
+				seenPages[page] = true
+				queue = append(queue, page)
+			}
 		}
-		curLink = linkPage.NextPage
 	}
 
-	linkOut := strings.Join(links, "\n") + "\n"
-	linkFile.WriteString(linkOut)
-
-	if s.onlyLinks {
-		return nil
+	if crawled == 0 && lastErr != nil {
+		return fmt.Errorf("error scraping for link: %w", lastErr)
 	}
 
-	log.Println("Starting scraping recipes")
-
-	for _, link := range links {
-		err := s.scrapeRecipe(ctx, link)
-		if err != nil {
-			msg := fmt.Sprintf("error scraping recipe at link %s: %s", link, err.Error())
-			log.Println(msg)
+	// Links are only added once all index pages are crawled, so an interrupted
+	// crawl of the index starts over. Only the links new to the crawl state are written,
+	// so a crawl that starts over does not write them again.
+	added, err := s.state.AddPending(links)
+	if err != nil {
+		return fmt.Errorf("could not update crawl state: %w", err)
+	}
+	if len(added) > 0 {
+		if _, err := linkFile.WriteString(strings.Join(added, "\n") + "\n"); err != nil {
+			return fmt.Errorf("could not write links: %w", err)
 		}
 	}
-
-	log.Println("Finishing scraping with Success!")
+	if err := s.state.MarkIndexed(s.startLink); err != nil {
+		return fmt.Errorf("could not update crawl state: %w", err)
+	}
 	return nil
 }
 
+// startPages returns the index pages the crawl starts from. Sitemaps are crawled from the
+// sitemaps robots.txt declares if the start link is the robots.txt or the root of a site,
+// or else from /sitemap.xml. Other index pages are crawled from the start link itself.
+func (s *Scraper) startPages(ctx context.Context) ([]string, error) {
+	if _, ok := s.linkSource.(linksource.PageLinkSource); !ok {
+		return []string{s.startLink}, nil
+	}
+	u, err := url.Parse(s.startLink)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start link %q: %w", s.startLink, err)
+	}
+	if u.Path != "" && u.Path != "/" && u.Path != "/robots.txt" {
+		return []string{s.startLink}, nil
+	}
+
+	rules, fetched, err := s.robots.rulesFor(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if fetched {
+		s.throttle.SetCrawlDelay(u.Host, rules.crawlDelay)
+	}
+	if len(rules.sitemaps) == 0 {
+		log.Println("robots.txt declares no sitemaps, crawling /sitemap.xml: " + s.startLink)
+		return []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}, nil
+	}
+	return rules.sitemaps, nil
+}
+
+// ScrapeFromLinksFile scrapes the recipes of a file with one link per line, skipping links
+// the crawl state already scraped.
 func (s *Scraper) ScrapeFromLinksFile(ctx context.Context, filepath string) error {
+	defer s.close()
+
 	linkFile, err := os.Open(filepath)
 	if err != nil {
 		return fmt.Errorf("could not open links file: %w", err)
@@ -106,64 +388,140 @@ func (s *Scraper) ScrapeFromLinksFile(ctx context.Context, filepath string) erro
 	}
 
 	links := strings.Split(strings.Trim(string(linkBytes), "\n"), "\n")
-	shuffle(links)
-	return s.scrapeRecipes(ctx, links)
-}
+	if _, err := s.state.AddPending(links); err != nil {
+		return fmt.Errorf("could not update crawl state: %w", err)
+	}
 
-func (s *Scraper) scrapeRecipes(ctx context.Context, links []string) error {
-	defer s.writer.Close()
-	defer s.writeLinks(&links)
+	pending := s.state.Pending(s.maxAttempts)
+	shuffle(pending)
+	return s.scrapeAll(ctx, pending)
+}
 
-	for _, link := range links {
-		err := s.scrapeRecipe(ctx, link)
-		if err != nil {
-			msg := fmt.Sprintf("error scraping recipe at link %s: %s", link, err.Error())
-			log.Println(msg)
+// scrapeAll scrapes the recipes at links with a pool of workers, one per allowed
+// concurrent request, and records the outcome of every link in the crawl state.
+// Failed recipes are logged and skipped. Links of hosts whose robots.txt is unreachable
+// are requeued once it may be fetched again, as many times as a link is attempted, and
+// stay pending after that. It stops handing out links once ctx is done.
+func (s *Scraper) scrapeAll(ctx context.Context, links []string) error {
+	// A drift stops the crawl with its own context, so that it is told apart from an
+	// interrupt. Either way the links in flight stay pending.
+	crawlCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.driftMutex.Lock()
+	s.cancel = cancel
+	s.driftMutex.Unlock()
+
+	for round := 1; len(links) > 0; round++ {
+		links = s.scrapeLinks(crawlCtx, links)
+		if len(links) == 0 || round >= s.maxAttempts || crawlCtx.Err() != nil {
+			break
+		}
+		log.Printf("Requeueing %d links whose robots.txt was unreachable, retrying in %s", len(links), robotsRetryTime)
+		if err := sleep(crawlCtx, robotsRetryTime); err != nil {
+			break
 		}
 	}
-	return nil
-}
 
-func (s *Scraper) writeLinks(links *[]string) error {
-	file, err := os.Create("remainingLinks.txt")
-	if err != nil {
-		log.Println("Error writing remaining links, printing instead")
-		for _, link := range *links {
-			log.Println(link)
-		}
-		return fmt.Errorf("could not create remaining links file: %w", err)
+	counts := s.state.Counts()
+	log.Printf("Crawl state: %d succeeded, %d failed, %d skipped, %d pending",
+		counts[StatusSucceeded], counts[StatusFailed], counts[StatusSkipped], counts[StatusPending])
+	if s.ownParseStats {
+		s.parseStats.Log()
 	}
-	defer file.Close()
 
-	linkOut := strings.Join(*links, "\n") + "\n"
-	_, err = file.WriteString(linkOut)
-	if err != nil {
-		log.Println("Error writing remaining links, printing instead")
-		for _, link := range *links {
-			log.Println(link)
-		}
-		return fmt.Errorf("could not write remaining links file: %w", err)
+	s.driftMutex.Lock()
+	defer s.driftMutex.Unlock()
+	if s.driftErr != nil {
+		return s.driftErr
 	}
+	return ctx.Err()
+}
 
-	return nil
+// scrapeLinks scrapes the recipes at links with a pool of workers and records their
+// outcome in the crawl state. It returns the links whose robots.txt was unreachable, which
+// are left pending.
+func (s *Scraper) scrapeLinks(ctx context.Context, links []string) []string {
+	linkChan := make(chan string)
+	go func() {
+		defer close(linkChan)
+		for _, link := range links {
+			select {
+			case linkChan <- link:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	requeued := make([]string, 0)
+	requeuedMutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < s.throttle.Concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range linkChan {
+				err := s.scrapeRecipe(ctx, link)
+				var stateErr error
+				switch {
+				case err == nil:
+					stateErr = s.state.MarkSucceeded(link)
+				case ctx.Err() != nil:
+					// Interrupted, leave the link pending for the next run.
+					continue
+				case errors.Is(err, ErrDisallowedByRobots):
+					stateErr = s.state.MarkSkipped(link, ErrDisallowedByRobots.Error())
+				case errors.Is(err, ErrRobotsUnavailable):
+					requeuedMutex.Lock()
+					requeued = append(requeued, link)
+					requeuedMutex.Unlock()
+				default:
+					msg := fmt.Sprintf("error scraping recipe at link %s: %s", link, err.Error())
+					log.Println(msg)
+					stateErr = s.state.MarkFailed(link, err)
+				}
+				if stateErr != nil {
+					log.Println("error updating crawl state: " + stateErr.Error())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return requeued
 }
 
 func (s *Scraper) scrapeRecipe(ctx context.Context, link string) error {
-	page, err := s.makeRequest(link)
+	// Fail before fetching a page no parser can handle.
+	source, err := s.sourceFor(link)
+	if err != nil {
+		return err
+	}
+
+	page, err := s.makeRequest(ctx, link, maxPageSize)
 	if err != nil {
 		return fmt.Errorf("error scraping recipe: %w", err)
 	}
 
-	node, err := html.Parse(page.Body)
+	node, err := html.Parse(bytes.NewReader(page))
 	if err != nil {
 		return fmt.Errorf("error parsing recipe: %w", err)
 	}
 
-	rawRecipe, err := s.parser.ParseRecipe(node)
+	rawRecipe, diagnostics, err := parser.Parse(source.Parser, node, s.fieldPolicy)
+	s.parseStats.Record(diagnostics, err)
+	s.recordDiagnostics(link, diagnostics, err)
+	if drift := s.drift.Record(source.Name, link, page, diagnostics); drift != nil {
+		s.reportDrift(drift)
+	}
 	if err != nil {
 		return fmt.Errorf("error parsing recipe: %w", err)
 	}
 	rawRecipe.Metadata.SourceURL = link
+	// Parsers resolve URLs against the base URL the page gives, if any, and the rest are
+	// relative to the page itself.
+	if pageURL, err := url.Parse(link); err == nil {
+		rawRecipe.Metadata.ResolveURLs(pageURL)
+	}
 
 	listFmt := make([]recipe.RawRecipe, 1)
 	listFmt[0] = *rawRecipe
@@ -173,7 +531,9 @@ func (s *Scraper) scrapeRecipe(ctx context.Context, link string) error {
 		return fmt.Errorf("error marshalling recipe: %w", err)
 	}
 
+	s.writerMutex.Lock()
 	_, err = s.writer.Write(append(yamlBytes, '\n'))
+	s.writerMutex.Unlock()
 	if err != nil {
 		return fmt.Errorf("error writing recipe: %w", err)
 	}
@@ -181,29 +541,203 @@ func (s *Scraper) scrapeRecipe(ctx context.Context, link string) error {
 	return nil
 }
 
+// indexSource returns the source that crawls the index pages from the start link.
+func (s *Scraper) indexSource() (*Source, error) {
+	if s.source != nil {
+		return s.source, nil
+	}
+	return s.sources.ForLink(s.startLink)
+}
+
+// sourceFor returns the source that parses the recipe at link: the configured source,
+// or else the source registered for its host.
+func (s *Scraper) sourceFor(link string) (*Source, error) {
+	if s.source != nil {
+		return s.source, nil
+	}
+	return s.sources.ForLink(link)
+}
+
 func (s *Scraper) scrapeForLink(ctx context.Context, link string) (*linksource.LinkPage, error) {
-	page, err := s.makeRequest(link)
-	log.Println("Link Page Response: ", page.Status)
+	pageSource, isPageSource := s.linkSource.(linksource.PageLinkSource)
+	maxSize := int64(maxPageSize)
+	if isPageSource {
+		maxSize = maxSitemapSize
+	}
+	page, err := s.makeRequest(ctx, link, maxSize)
 	if err != nil {
 		return nil, fmt.Errorf("error scraping for links: %w", err)
 	}
 
-	node, err := html.Parse(page.Body)
+	var links *linksource.LinkPage
+	if isPageSource {
+		links, err = pageSource.GetPageLinks(page)
+	} else {
+		node, parseErr := html.Parse(bytes.NewReader(page))
+		if parseErr != nil {
+			return nil, fmt.Errorf("error parsing for links: %w", parseErr)
+		}
+		links, err = s.linkSource.GetLinks(node)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error parsing for links: %w", err)
 	}
+	return links, nil
+}
 
-	links, err := s.linkSource.GetLinks(node)
+// makeRequest returns the page at link, at most maxSize bytes of it, if robots.txt of its
+// host allows it. Transient failures are retried with exponential backoff, waiting at
+// least as long as the server's Retry-After. Disallowed links are recorded as skipped
+// and return ErrDisallowedByRobots, other failures return a *FetchError.
+func (s *Scraper) makeRequest(ctx context.Context, link string, maxSize int64) ([]byte, error) {
+	u, err := url.Parse(link)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing for links: %w", err)
+		return nil, fmt.Errorf("invalid link %q: %w", link, err)
 	}
-	return links, nil
+
+	rules, fetched, err := s.robots.rulesFor(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if fetched {
+		s.throttle.SetCrawlDelay(u.Host, rules.crawlDelay)
+	}
+	if !rules.Allowed(u.RequestURI()) {
+		s.recordSkipped(link, ErrDisallowedByRobots.Error())
+		return nil, ErrDisallowedByRobots
+	}
+
+	bOff := backoff.NewExponentialBackOff()
+	bOff.InitialInterval = s.retry.InitialBackoff
+	bOff.MaxInterval = s.retry.MaxBackoff
+	bOff.MaxElapsedTime = 0
+	// The backoff starts from its default interval until it is reset.
+	bOff.Reset()
+
+	for retries := 0; ; retries++ {
+		page, err := s.fetchPage(ctx, link, maxSize)
+		var fetchErr *FetchError
+		if err == nil || !errors.As(err, &fetchErr) || fetchErr.Class != FailureTransient || retries >= s.retry.MaxRetries {
+			return page, err
+		}
+
+		wait := bOff.NextBackOff()
+		if fetchErr.RetryAfter > wait {
+			wait = fetchErr.RetryAfter
+		}
+		log.Printf("%s, retrying in %s", err.Error(), wait)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchPage fetches at most maxSize bytes of the page at link once and classifies the
+// response.
+func (s *Scraper) fetchPage(ctx context.Context, link string, maxSize int64) ([]byte, error) {
+	resp, err := s.fetch(ctx, link)
+	if err != nil {
+		return nil, classifyError(link, err)
+	}
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, classifyError(link, err)
+	}
+	if fetchErr := classifyResponse(link, resp, page); fetchErr != nil {
+		return nil, fetchErr
+	}
+	return page, nil
 }
 
-func (s *Scraper) makeRequest(link string) (*http.Response, error) {
-	timeToSleep := int64(rand.Float64()*10) + 2
-	time.Sleep(time.Duration(timeToSleep) * time.Second)
-	return http.Get(link)
+// fetch makes a GET request for link with the configured headers and user agent once
+// the throttle allows a request to its host.
+func (s *Scraper) fetch(ctx context.Context, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+
+	release, err := s.throttle.Acquire(ctx, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.client.Do(req)
+}
+
+// reportDrift logs a drift and saves its samples. The first drift of a crawl is returned
+// by it, and stops it if the drift action is abort.
+func (s *Scraper) reportDrift(drift *DriftError) {
+	if err := drift.saveSamples(s.driftDir); err != nil {
+		log.Println(err.Error())
+	}
+	drift.log()
+
+	s.driftMutex.Lock()
+	defer s.driftMutex.Unlock()
+	if s.driftErr == nil {
+		s.driftErr = drift
+	}
+	if s.driftAction == DriftAbort && s.cancel != nil {
+		log.Println("Stopping the crawl, the remaining links stay pending")
+		s.cancel()
+	}
+}
+
+// recipeDiagnostics is a line of the diagnostics file: the fields of a recipe that were
+// not found, and whether the recipe was kept anyway.
+type recipeDiagnostics struct {
+	Link     string                `json:"link"`
+	Kept     bool                  `json:"kept"`
+	Error    string                `json:"error,omitempty"`
+	Fields   []parser.FieldReport  `json:"fields,omitempty"`
+	Warnings []parser.ParseWarning `json:"warnings,omitempty"`
+}
+
+// recordDiagnostics writes the fields of the recipe at link that were not found to the
+// diagnostics file. Recipes that have every field are left out.
+func (s *Scraper) recordDiagnostics(link string, diagnostics *parser.Diagnostics, err error) {
+	if s.diagnosticsWriter == nil || diagnostics == nil {
+		return
+	}
+
+	record := recipeDiagnostics{Link: link, Kept: err == nil, Warnings: diagnostics.Warnings}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	for _, report := range diagnostics.Fields {
+		if report.Status != parser.FieldFound {
+			record.Fields = append(record.Fields, report)
+		}
+	}
+	if record.Kept && len(record.Fields) == 0 && len(record.Warnings) == 0 {
+		return
+	}
+
+	line, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		log.Println("error marshalling diagnostics: " + marshalErr.Error())
+		return
+	}
+	s.diagnosticsMutex.Lock()
+	defer s.diagnosticsMutex.Unlock()
+	s.diagnosticsWriter.Write(append(line, '\n'))
+}
+
+// recordSkipped logs a link that was not fetched for policy reasons and writes it to
+// the skipped links file, so the link file stays auditable.
+func (s *Scraper) recordSkipped(link, reason string) {
+	log.Printf("skipping %s: %s", link, reason)
+
+	s.skippedMutex.Lock()
+	defer s.skippedMutex.Unlock()
+	fmt.Fprintf(s.skippedWriter, "%s\t%s\n", link, reason)
 }
 
 func shuffle(links []string) {
@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestScraper creates a scraper that writes to a temporary directory, does not
+// throttle and backs off for a millisecond.
+func newTestScraper(t *testing.T) *Scraper {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.SourceType = "jsonld"
+	cfg.OutputPath = filepath.Join(dir, "recipes.yaml")
+	cfg.LinksPath = filepath.Join(dir, "links.tmp")
+	cfg.StatePath = filepath.Join(dir, "crawlstate.jsonl")
+	cfg.SkippedLinksPath = filepath.Join(dir, "skippedLinks.txt")
+	cfg.DiagnosticsPath = ""
+	cfg.UserAgent = "RecipeScraperTest/1.0"
+	cfg.ThrottleConfig = ThrottleConfig{Concurrency: 4, RequestsPerSecond: 1000, Burst: 100}
+	cfg.RetryConfig = RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	s, err := NewScraper(cfg)
+	if err != nil {
+		t.Fatalf("NewScraper() = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.close(); err != nil {
+			t.Errorf("close() = %v", err)
+		}
+	})
+	return s
+}
+
+// testRecipePage is a page with a schema.org recipe.
+const testRecipePage = `<html><head><script type="application/ld+json">
+{"@context": "https://schema.org", "@type": "Recipe", "name": "Pancakes",
+ "recipeIngredient": ["1 cup flour", "1 egg"], "recipeInstructions": ["Mix.", "Fry."]}
+</script></head><body></body></html>`
+
+func TestMakeRequestRetriesAndClassifies(t *testing.T) {
+	// Each path answers with its statuses in turn, and then with the last one.
+	responses := map[string][]int{
+		"/robots.txt":    {http.StatusNotFound},
+		"/rate-limited":  {http.StatusTooManyRequests, http.StatusOK},
+		"/unavailable":   {http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+		"/missing":       {http.StatusNotFound},
+		"/always-failed": {http.StatusServiceUnavailable},
+	}
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	userAgents := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		statuses := responses[r.URL.Path]
+		hit := hits[r.URL.Path]
+		hits[r.URL.Path]++
+		userAgents[r.UserAgent()] = true
+		mu.Unlock()
+
+		if statuses == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		status := statuses[len(statuses)-1]
+		if hit < len(statuses) {
+			status = statuses[hit]
+		}
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte("<html><body>ok</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path      string
+		wantClass FailureClass
+		wantCode  int
+		wantHits  int
+	}{
+		{"/rate-limited", "", 0, 2},
+		{"/unavailable", "", 0, 3},
+		{"/missing", FailurePermanent, http.StatusNotFound, 1},
+		{"/always-failed", FailureTransient, http.StatusServiceUnavailable, 3},
+	}
+	s := newTestScraper(t)
+	for _, test := range tests {
+		page, err := s.makeRequest(context.Background(), server.URL+test.path, maxPageSize)
+		mu.Lock()
+		gotHits := hits[test.path]
+		mu.Unlock()
+		if gotHits != test.wantHits {
+			t.Errorf("%s: %d requests, want %d", test.path, gotHits, test.wantHits)
+		}
+
+		if test.wantClass == "" {
+			if err != nil {
+				t.Errorf("%s: makeRequest() = %v", test.path, err)
+			} else if string(page) != "<html><body>ok</body></html>" {
+				t.Errorf("%s: makeRequest() = %q", test.path, page)
+			}
+			continue
+		}
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) {
+			t.Errorf("%s: makeRequest() error = %v, want a *FetchError", test.path, err)
+			continue
+		}
+		if fetchErr.Class != test.wantClass || fetchErr.StatusCode != test.wantCode {
+			t.Errorf("%s: makeRequest() failed %s with %d, want %s with %d", test.path, fetchErr.Class, fetchErr.StatusCode, test.wantClass, test.wantCode)
+		}
+	}
+
+	if len(userAgents) != 1 || !userAgents["RecipeScraperTest/1.0"] {
+		t.Errorf("requests were sent with user agents %v, want only RecipeScraperTest/1.0", userAgents)
+	}
+}
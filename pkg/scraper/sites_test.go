@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testSiteDefinition = `
+name: foodnetwork
+parser:
+  name:
+    selector: h1.title
+    required: true
+  ingredients:
+    selector: li.ingredient
+    required: true
+  steps:
+    selector: li.step
+    required: true
+`
+
+func TestLoadSitesReplacesSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foodnetwork.yaml"), []byte(testSiteDefinition), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a site"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry := DefaultSources.Clone()
+	if err := registry.LoadSites([]string{dir}); err != nil {
+		t.Fatalf("LoadSites() = %v", err)
+	}
+
+	source, err := registry.Lookup("foodnetwork")
+	if err != nil {
+		t.Fatalf("Lookup() = %v", err)
+	}
+	builtin, _ := DefaultSources.Lookup("foodnetwork")
+	if source == builtin || DefaultSources.sources["foodnetwork"] != builtin {
+		t.Error("LoadSites() did not replace the source in the clone only")
+	}
+	if source.LinkSource != builtin.LinkSource || len(source.Hosts) != 1 || source.Hosts[0] != "foodnetwork.com" {
+		t.Errorf("replaced source = %+v, want the index pages and hosts of the built-in source", source)
+	}
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		{`<html><body><h1 class="title">Selected Pie</h1><ul><li class="ingredient">1 apple</li></ul>
+<ol><li class="step">Bake.</li></ol></body></html>`, "Selected Pie"},
+		// Pages the selectors no longer match fall back to the structured data.
+		{testRecipePage, "Pancakes"},
+	}
+	for _, test := range tests {
+		node, err := html.Parse(strings.NewReader(test.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawRecipe, err := source.Parser.ParseRecipe(node)
+		if err != nil || rawRecipe.Name != test.want {
+			t.Errorf("ParseRecipe() = %v, %v, want %s", rawRecipe, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/linksource"
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+)
+
+// ErrUnknownSource is returned when no registered source matches a name or link.
+var ErrUnknownSource = errors.New("unknown source")
+
+// Source is a site the scraper knows how to crawl and parse.
+type Source struct {
+	Name string
+	// Hosts are the hosts the source's recipes live on. A host also matches its
+	// subdomains, so foodnetwork.com matches www.foodnetwork.com.
+	Hosts []string
+	// LinkSource finds recipe links on index pages. It may be nil if the source is
+	// only scraped from a links file.
+	LinkSource linksource.LinkSource
+	Parser     parser.Parser
+}
+
+// hostMatch returns the length of the longest of the source's hosts that host is, or
+// is a subdomain of, or -1 if none match.
+func (s *Source) hostMatch(host string) int {
+	host = strings.ToLower(host)
+	longest := -1
+	for _, pattern := range s.Hosts {
+		pattern = strings.ToLower(pattern)
+		if (host == pattern || strings.HasSuffix(host, "."+pattern)) && len(pattern) > longest {
+			longest = len(pattern)
+		}
+	}
+	return longest
+}
+
+// SourceRegistry holds the sources the scraper can scrape, by name and by host.
+type SourceRegistry struct {
+	mu      sync.RWMutex
+	sources map[string]*Source
+}
+
+// NewSourceRegistry creates an empty registry.
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{sources: make(map[string]*Source)}
+}
+
+// DefaultSources is the registry used by scrapers that are not given one. Every
+// built-in source is registered in it.
+var DefaultSources = NewSourceRegistry()
+
+func init() {
+	DefaultSources.MustRegister(&Source{
+		Name:       "foodnetwork",
+		Hosts:      []string{"foodnetwork.com"},
+		LinkSource: linksource.NewFoodnetworkLinkSource(),
+		// The page's structured data is the fallback when the selectors break.
+		Parser: parser.NewChainParser(parser.NewFoodnetworkParser(), parser.NewJSONLDParser(), parser.NewMicrodataParser()),
+	})
+	DefaultSources.MustRegister(&Source{
+		Name:       "wikibooks",
+		Hosts:      []string{"wikibooks.org"},
+		LinkSource: linksource.NewWikibooksLinkSource(),
+		Parser:     parser.NewChainParser(parser.NewWikibooksParser(), parser.NewMicrodataParser()),
+	})
+	// Any site with a sitemap of recipes that are marked up with schema.org, crawled from
+	// the sitemaps its robots.txt declares.
+	sitemaps, err := linksource.NewSitemapLinkSource(linksource.SitemapLinkSourceConfig{})
+	if err != nil {
+		panic(err)
+	}
+	DefaultSources.MustRegister(&Source{
+		Name:       "sitemap",
+		LinkSource: sitemaps,
+		Parser:     parser.NewChainParser(parser.NewJSONLDParser(), parser.NewMicrodataParser()),
+	})
+	// Any site that embeds a schema.org Recipe as JSON-LD, for links files of sites
+	// without a source of their own.
+	DefaultSources.MustRegister(&Source{
+		Name:   "jsonld",
+		Parser: parser.NewJSONLDParser(),
+	})
+	// Any site that marks a schema.org Recipe up with microdata or RDFa.
+	DefaultSources.MustRegister(&Source{
+		Name:   "microdata",
+		Parser: parser.NewMicrodataParser(),
+	})
+}
+
+// Register adds a source. It returns an error if the source has no name or parser,
+// or if a source with the same name is already registered.
+func (r *SourceRegistry) Register(source *Source) error {
+	if source.Name == "" {
+		return errors.New("source has no name")
+	}
+	if source.Parser == nil {
+		return fmt.Errorf("source %q has no parser", source.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sources[source.Name]; ok {
+		return fmt.Errorf("source %q is already registered", source.Name)
+	}
+	r.sources[source.Name] = source
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for registering
+// built-in sources at init time.
+func (r *SourceRegistry) MustRegister(source *Source) {
+	if err := r.Register(source); err != nil {
+		panic(err)
+	}
+}
+
+// Replace registers a source, replacing the source of the same name if there is one.
+// The old parser, with its structured data fallbacks, is tried when the new one fails to
+// parse a recipe. If the new source cannot crawl index pages, it keeps those of the old one.
+func (r *SourceRegistry) Replace(source *Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.sources[source.Name]; ok {
+		source.Parser = parser.NewChainParser(source.Parser, old.Parser)
+		if source.LinkSource == nil {
+			source.LinkSource = old.LinkSource
+		}
+		if len(source.Hosts) == 0 {
+			source.Hosts = old.Hosts
+		}
+	}
+	r.sources[source.Name] = source
+}
+
+// Clone returns a registry with the same sources, which can be changed without changing r.
+func (r *SourceRegistry) Clone() *SourceRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewSourceRegistry()
+	for name, source := range r.sources {
+		clone.sources[name] = source
+	}
+	return clone
+}
+
+// Lookup returns the source registered under name.
+func (r *SourceRegistry) Lookup(name string) (*Source, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	source, ok := r.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, known sources are %s", ErrUnknownSource, name, strings.Join(r.namesLocked(), ", "))
+	}
+	return source, nil
+}
+
+// ForLink returns the source whose hosts match the host of link. If several match,
+// the one with the longest matching host wins, and of those the first by name.
+func (r *SourceRegistry) ForLink(link string) (*Source, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link %q: %w", link, err)
+	}
+	host := u.Hostname()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *Source
+	bestLen := -1
+	for _, name := range r.namesLocked() {
+		source := r.sources[name]
+		if matchLen := source.hostMatch(host); matchLen > bestLen {
+			best = source
+			bestLen = matchLen
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w for host %q", ErrUnknownSource, host)
+	}
+	return best, nil
+}
+
+// Names returns the names of the registered sources in order.
+func (r *SourceRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+// namesLocked returns the sorted source names. r.mu must be held.
+func (r *SourceRegistry) namesLocked() []string {
+	names := make([]string, 0, len(r.sources))
+	for name := range r.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/CS446-S23-Group35/RecipeScraper/pkg/parser"
+)
+
+func TestSourceRegistryForLink(t *testing.T) {
+	registry := NewSourceRegistry()
+	for _, source := range []*Source{
+		{Name: "recipes", Hosts: []string{"example.com"}},
+		{Name: "pies", Hosts: []string{"pies.example.com"}},
+		// Registered first, but cakes is first by name, so it wins the tie.
+		{Name: "zcakes", Hosts: []string{"cakes.example.com"}},
+		{Name: "cakes", Hosts: []string{"cakes.example.com"}},
+	} {
+		source.Parser = parser.NewJSONLDParser()
+		registry.MustRegister(source)
+	}
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://example.com/recipe", "recipes"},
+		{"https://www.example.com/recipe", "recipes"},
+		{"https://pies.example.com/apple", "pies"},
+		{"https://www.PIES.example.com/apple", "pies"},
+		{"https://cakes.example.com/lemon", "cakes"},
+		{"https://notexample.com/recipe", ""},
+	}
+	for _, test := range tests {
+		// Ties are broken the same way on every call.
+		for i := 0; i < 10; i++ {
+			source, err := registry.ForLink(test.link)
+			if test.want == "" {
+				if !errors.Is(err, ErrUnknownSource) {
+					t.Errorf("ForLink(%s) = %v, want ErrUnknownSource", test.link, err)
+				}
+				break
+			}
+			if err != nil || source.Name != test.want {
+				t.Errorf("ForLink(%s) = %v, %v, want %s", test.link, source, err, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestScraperSourceFor(t *testing.T) {
+	tests := []struct {
+		sourceType string
+		link       string
+		want       string
+	}{
+		// A configured source parses every link, even those of another source's host.
+		{"jsonld", "https://www.foodnetwork.com/recipes/pie", "jsonld"},
+		{"microdata", "https://en.wikibooks.org/wiki/Cookbook:Pie", "microdata"},
+		// Without one, the source is picked by host.
+		{"", "https://www.foodnetwork.com/recipes/pie", "foodnetwork"},
+		{"", "https://en.wikibooks.org/wiki/Cookbook:Pie", "wikibooks"},
+		{"", "https://www.example.com/recipe", ""},
+	}
+	s := newTestScraper(t)
+	for _, test := range tests {
+		s.source = nil
+		if test.sourceType != "" {
+			source, err := s.sources.Lookup(test.sourceType)
+			if err != nil {
+				t.Fatalf("Lookup(%s) = %v", test.sourceType, err)
+			}
+			s.source = source
+		}
+		source, err := s.sourceFor(test.link)
+		if test.want == "" {
+			if !errors.Is(err, ErrUnknownSource) {
+				t.Errorf("%q: sourceFor(%s) = %v, want ErrUnknownSource", test.sourceType, test.link, err)
+			}
+			continue
+		}
+		if err != nil || source.Name != test.want {
+			t.Errorf("%q: sourceFor(%s) = %v, %v, want %s", test.sourceType, test.link, source, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package scraper
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig configures how politely the scraper crawls.
+type ThrottleConfig struct {
+	// Concurrency is the maximum number of requests in flight across all hosts.
+	Concurrency int `yaml:"concurrency"`
+	// RequestsPerSecond and Burst define the token bucket of each host.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	// Jitter is the maximum random delay added before each request.
+	Jitter time.Duration `yaml:"jitter"`
+}
+
+// DefaultThrottleConfig returns a throttle that sends each host about one request
+// every 5 to 10 seconds.
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		Concurrency:       4,
+		RequestsPerSecond: 0.2,
+		Burst:             1,
+		Jitter:            5 * time.Second,
+	}
+}
+
+// Throttle limits how fast and how many requests are made. Every host gets its own
+// token bucket, and the number of requests in flight is capped across all hosts.
+// A Throttle can be shared by several scrapers so they crawl in parallel while each
+// host still sees polite traffic.
+type Throttle struct {
+	rate   float64
+	burst  float64
+	jitter time.Duration
+	slots  chan struct{}
+
+	mu    sync.Mutex
+	hosts map[string]*hostBucket
+}
+
+// hostBucket is the token bucket of a single host.
+type hostBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewThrottle creates a throttle, replacing non-positive settings with their defaults.
+func NewThrottle(cfg ThrottleConfig) *Throttle {
+	defaults := DefaultThrottleConfig()
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaults.Concurrency
+	}
+	if cfg.RequestsPerSecond <= 0 {
+		cfg.RequestsPerSecond = defaults.RequestsPerSecond
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = defaults.Burst
+	}
+	if cfg.Jitter < 0 {
+		cfg.Jitter = 0
+	}
+
+	return &Throttle{
+		rate:   cfg.RequestsPerSecond,
+		burst:  float64(cfg.Burst),
+		jitter: cfg.Jitter,
+		slots:  make(chan struct{}, cfg.Concurrency),
+		hosts:  make(map[string]*hostBucket),
+	}
+}
+
+// Concurrency returns the maximum number of requests in flight.
+func (t *Throttle) Concurrency() int {
+	return cap(t.slots)
+}
+
+// Acquire blocks until a request to host is allowed, and returns a function that must
+// be called once the request is done. It returns an error if ctx is done first.
+func (t *Throttle) Acquire(ctx context.Context, host string) (func(), error) {
+	if err := sleep(ctx, t.reserve(host)+t.randomJitter()); err != nil {
+		return nil, err
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		return func() { <-t.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SetCrawlDelay slows host down to at most one request per delay, as asked for by
+// the Crawl-delay of its robots.txt. It never speeds a host up.
+func (t *Throttle) SetCrawlDelay(host string, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket := t.bucket(host, time.Now())
+	rate := 1 / delay.Seconds()
+	if rate < bucket.rate {
+		bucket.rate = rate
+	}
+	bucket.burst = 1
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// bucket returns the bucket of host, creating a full one if needed. t.mu must be held.
+func (t *Throttle) bucket(host string, now time.Time) *hostBucket {
+	bucket, ok := t.hosts[host]
+	if !ok {
+		bucket = &hostBucket{rate: t.rate, burst: t.burst, tokens: t.burst, last: now}
+		t.hosts[host] = bucket
+	}
+	return bucket
+}
+
+// reserve takes a token from the bucket of host and returns how long to wait until
+// the token is actually available.
+func (t *Throttle) reserve(host string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	bucket := t.bucket(host, now)
+
+	// Refill for the time passed, then take a token. A negative balance is a
+	// debt that the caller waits off.
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+	bucket.tokens--
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+func (t *Throttle) randomJitter() time.Duration {
+	if t.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.jitter)))
+}
+
+// sleep pauses for d, or returns early with an error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottleReserve(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{Concurrency: 1, RequestsPerSecond: 10, Burst: 2})
+
+	// The burst of a host is free, after which each request waits for its token.
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := throttle.reserve("a.example.com"); got < want-10*time.Millisecond || got > want {
+			t.Errorf("request %d to a host: reserve() = %s, want %s", i, got, want)
+		}
+	}
+	// Every host has a bucket of its own.
+	if got := throttle.reserve("b.example.com"); got != 0 {
+		t.Errorf("first request to another host: reserve() = %s, want 0", got)
+	}
+
+	// A crawl delay slows a host down, but never speeds it up.
+	throttle.SetCrawlDelay("c.example.com", 2*time.Second)
+	throttle.SetCrawlDelay("c.example.com", time.Millisecond)
+	throttle.reserve("c.example.com")
+	if got := throttle.reserve("c.example.com"); got < 1900*time.Millisecond || got > 2*time.Second {
+		t.Errorf("request after a crawl delay: reserve() = %s, want 2s", got)
+	}
+}
+
+func TestThrottleAcquire(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{Concurrency: 1, RequestsPerSecond: 1000, Burst: 100})
+	if throttle.Concurrency() != 1 {
+		t.Fatalf("Concurrency() = %d, want 1", throttle.Concurrency())
+	}
+
+	release, err := throttle.Acquire(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+	// The only slot is taken, so another request waits until its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := throttle.Acquire(ctx, "b.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() with every slot taken = %v, want context.DeadlineExceeded", err)
+	}
+
+	release()
+	release, err = throttle.Acquire(context.Background(), "b.example.com")
+	if err != nil {
+		t.Fatalf("Acquire() after a release = %v", err)
+	}
+	release()
+}
+
+func TestNewThrottleDefaults(t *testing.T) {
+	throttle := NewThrottle(ThrottleConfig{Jitter: -time.Second})
+	defaults := DefaultThrottleConfig()
+	if throttle.Concurrency() != defaults.Concurrency || throttle.rate != defaults.RequestsPerSecond ||
+		throttle.burst != float64(defaults.Burst) || throttle.jitter != 0 {
+		t.Errorf("NewThrottle() of an empty config = %+v, want the defaults without jitter", throttle)
+	}
+}